@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+// CurrentJobStub returns the job the cursor is on in the Jobs panel
+func (trekState *trekStateType) CurrentJobStub() (nomad.JobListStub, error) {
+	index := trekState.selectedJob
+	if index < 0 || index > len(trekState.jobs)-1 {
+		return nomad.JobListStub{}, newTrekError(NotFoundError, nil, "job not found")
+	}
+	return trekState.jobs[index], nil
+}
+
+// mutating disables a handler changing the cluster when the cluster is
+// read-only
+func mutating(handler uiHandlerWithStateType) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		if trekState.ReadOnly() {
+			return openPopup(g, v, trekState, trekState.readOnlyError().Error())
+		}
+		return handler(g, v, trekState)
+	}
+}
+
+// reportEvaluation tells the user an action succeeded, along with the
+// evaluation it triggered
+func reportEvaluation(g *gocui.Gui, trekState *trekStateType, message string, evalID string) error {
+	if evalID != "" {
+		message = fmt.Sprintf("%s (evaluation %s)", message, shortID(evalID))
+	}
+	return openPopup(g, g.CurrentView(), trekState, message)
+}
+
+func reportActionError(g *gocui.Gui, trekState *trekStateType, err error) error {
+	return openPopup(g, g.CurrentView(), trekState, err.Error())
+}
+
+// stopJob stops (or purges) the job selected in the Jobs panel
+func stopJob(purge bool) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		job, err := trekState.CurrentJobStub()
+		if err != nil {
+			return openPopup(g, v, trekState, err.Error())
+		}
+
+		question, done := "Stop job %s?", "Job %s stopped"
+		if purge {
+			question, done = "Stop and purge job %s?", "Job %s purged"
+		}
+		return openConfirm(g, v, trekState, fmt.Sprintf(question, job.ID), func(g *gocui.Gui, trekState *trekStateType) error {
+			evalID, _, err := trekState.client.Jobs().Deregister(job.ID, purge, &nomad.WriteOptions{Namespace: job.Namespace})
+			if err != nil {
+				return reportActionError(g, trekState, apiError(err, "cannot stop job %s", job.ID))
+			}
+			return reportEvaluation(g, trekState, fmt.Sprintf(done, job.ID), evalID)
+		})
+	}
+}
+
+// forcePeriodicJob launches a periodic job right away
+func forcePeriodicJob(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	job, err := trekState.CurrentJobStub()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	if !job.Periodic {
+		return openPopup(g, v, trekState, fmt.Sprintf("job %s is not periodic", job.ID))
+	}
+
+	return openConfirm(g, v, trekState, fmt.Sprintf("Force a launch of periodic job %s?", job.ID), func(g *gocui.Gui, trekState *trekStateType) error {
+		evalID, _, err := trekState.client.Jobs().PeriodicForce(job.ID, &nomad.WriteOptions{Namespace: job.Namespace})
+		if err != nil {
+			return reportActionError(g, trekState, apiError(err, "cannot force periodic job %s", job.ID))
+		}
+		return reportEvaluation(g, trekState, fmt.Sprintf("Periodic job %s launched", job.ID), evalID)
+	})
+}
+
+// parseDispatchMeta parses comma-separated key=value pairs
+func parseDispatchMeta(value string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, newTrekError(GenericError, nil, "invalid meta %q, expected key=value", pair)
+		}
+		meta[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return meta, nil
+}
+
+// parseDispatchPayload reads a payload given inline, or from a local file when
+// prefixed with @
+func parseDispatchPayload(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(value, "@") {
+		payload, err := ioutil.ReadFile(value[1:])
+		if err != nil {
+			return nil, newTrekError(GenericError, err, "cannot read payload")
+		}
+		return payload, nil
+	}
+	return []byte(value), nil
+}
+
+// dispatchJob dispatches a parameterized job, asking for its meta and
+// payload first
+func dispatchJob(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	job, err := trekState.CurrentJobStub()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	if !job.ParameterizedJob {
+		return openPopup(g, v, trekState, fmt.Sprintf("job %s is not parameterized", job.ID))
+	}
+
+	return openPrompt(g, v, trekState, "Meta (key=value,...)", func(g *gocui.Gui, trekState *trekStateType, value string) error {
+		meta, err := parseDispatchMeta(value)
+		if err != nil {
+			return reportActionError(g, trekState, err)
+		}
+
+		return openPrompt(g, g.CurrentView(), trekState, "Payload (text, @file or empty)", func(g *gocui.Gui, trekState *trekStateType, value string) error {
+			payload, err := parseDispatchPayload(value)
+			if err != nil {
+				return reportActionError(g, trekState, err)
+			}
+
+			return openConfirm(g, g.CurrentView(), trekState, fmt.Sprintf("Dispatch job %s?", job.ID), func(g *gocui.Gui, trekState *trekStateType) error {
+				response, _, err := trekState.client.Jobs().Dispatch(job.ID, meta, payload, &nomad.WriteOptions{Namespace: job.Namespace})
+				if err != nil {
+					return reportActionError(g, trekState, apiError(err, "cannot dispatch job %s", job.ID))
+				}
+				return reportEvaluation(g, trekState, fmt.Sprintf("Job %s dispatched as %s", job.ID, response.DispatchedJobID), response.EvalID)
+			})
+		})
+	})
+}
+
+// scaleTaskGroup changes the count of the task group selected in the Task
+// Groups panel
+func scaleTaskGroup(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	taskGroups := trekState.CurrentTaskGroups()
+	if trekState.selectedAllocationGroup < 0 || trekState.selectedAllocationGroup > len(taskGroups)-1 {
+		return nil
+	}
+	job := trekState.CurrentJob()
+	group := *taskGroups[trekState.selectedAllocationGroup].Name
+	current := *taskGroups[trekState.selectedAllocationGroup].Count
+
+	return openPrompt(g, v, trekState, fmt.Sprintf("Count of %s (currently %d)", group, current), func(g *gocui.Gui, trekState *trekStateType, value string) error {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return reportActionError(g, trekState, newTrekError(GenericError, nil, "invalid count %q", value))
+		}
+
+		return openConfirm(g, g.CurrentView(), trekState, fmt.Sprintf("Scale %s of job %s from %d to %d?", group, *job.ID, current, count), func(g *gocui.Gui, trekState *trekStateType) error {
+			options := &nomad.WriteOptions{}
+			if job.Namespace != nil {
+				options.Namespace = *job.Namespace
+			}
+			response, _, err := trekState.client.Jobs().Scale(*job.ID, group, &count, "scaled from trek", false, nil, options)
+			if err != nil {
+				return reportActionError(g, trekState, apiError(err, "cannot scale %s", group))
+			}
+			return reportEvaluation(g, trekState, fmt.Sprintf("%s scaled to %d", group, count), response.EvalID)
+		})
+	})
+}
+
+// restartAllocation restarts all the tasks of the selected allocation
+func restartAllocation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	alloc, err := trekState.CurrentAllocation()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	allocation := alloc.allocation
+
+	return openConfirm(g, v, trekState, fmt.Sprintf("Restart allocation %s?", shortID(allocation.ID)), func(g *gocui.Gui, trekState *trekStateType) error {
+		if err := trekState.client.Allocations().Restart(&allocation, "", &nomad.QueryOptions{}); err != nil {
+			return reportActionError(g, trekState, apiError(err, "cannot restart allocation %s", shortID(allocation.ID)))
+		}
+		return reportEvaluation(g, trekState, fmt.Sprintf("Allocation %s restarted", shortID(allocation.ID)), "")
+	})
+}
+
+// stopAllocation stops the selected allocation, which gets rescheduled
+func stopAllocation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	alloc, err := trekState.CurrentAllocation()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	allocation := alloc.allocation
+
+	return openConfirm(g, v, trekState, fmt.Sprintf("Stop allocation %s?", shortID(allocation.ID)), func(g *gocui.Gui, trekState *trekStateType) error {
+		response, err := trekState.client.Allocations().Stop(&allocation, &nomad.QueryOptions{})
+		if err != nil {
+			return reportActionError(g, trekState, apiError(err, "cannot stop allocation %s", shortID(allocation.ID)))
+		}
+		return reportEvaluation(g, trekState, fmt.Sprintf("Allocation %s stopped", shortID(allocation.ID)), response.EvalID)
+	})
+}
@@ -2,21 +2,29 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
 )
 
 func runCommand(trekOptions trekOptions) error {
 	trekState := new(trekStateType)
 
-	trekState.nomadConnectConfiguration.addEnvironment("default", trekOptions.nomadAddress)
+	trekState.nomadConnectConfiguration.addEnvironment(trekOptions.defaultEnvironment())
 	trekState.selectedClusterIndex = 0
+	trekState.allocationStatusFilter = trekOptions.allocStatuses
+	trekState.readOnly = trekOptions.readOnly
 
 	if err := trekState.Connect(); err != nil {
-		log.Panicln(err)
 		return err
 	}
 
+	printDetails := func(provider interface{}) error {
+		return trekPrintOutput(os.Stdout, trekOptions.outputFormat, trekOptions.displayFormat, provider)
+	}
+
 	switch trekOptions.trekMode {
 	case ListJobsMode:
 
@@ -24,17 +32,90 @@ func runCommand(trekOptions trekOptions) error {
 			trekOptions.displayFormat = jobsListFormat
 		}
 
+		jobs, err := trekState.Jobs()
+		if err != nil {
+			return err
+		}
+
 		provider := jobsFormatProvider{
-			Jobs: buildJobs(trekState.Jobs()),
+			Jobs: buildJobs(jobs),
+		}
+		return printDetails(provider)
+
+	case ListNodesMode:
+
+		if trekOptions.displayFormat == "" {
+			trekOptions.displayFormat = nodesListFormat
+		}
+
+		nodes, err := trekState.Nodes()
+		if err != nil {
+			return err
+		}
+
+		provider := nodesFormatProvider{
+			Nodes: buildNodes(nodes),
+		}
+		return printDetails(provider)
+
+	case NodeMode:
+
+		if err := selectNodeByName(trekState, trekOptions.nodeName); err != nil {
+			return err
 		}
-		trekPrintDetails(os.Stdout, trekOptions.displayFormat, provider)
+
+		if trekOptions.displayFormat == "" {
+			trekOptions.displayFormat = nodeDetailsFormat
+		}
+
+		node, _ := trekState.CurrentNode()
+		provider, err := trekState.nodeDetails(node.ID)
+		if err != nil {
+			return err
+		}
+		return printDetails(provider)
+
+	case PlanMode:
+
+		return printPlan(trekState, trekOptions)
 
 	case JobMode:
 
-		for index, job := range trekState.Jobs() {
-			if *job.Name == trekOptions.jobID {
-				trekState.selectedJob = index
+		if trekOptions.allocationID != "" {
+			// Allocation selected by ID, the job and task group are deduced from it
+			if err := selectAllocationByID(trekState, trekOptions); err != nil {
+				return err
+			}
+		} else if err := selectJobByName(trekState, trekOptions.jobID); err != nil {
+			return err
+		}
+
+		if trekOptions.spec != "" {
+			job := trekState.CurrentJob()
+			spec, err := formatJobSpec(&job, trekOptions.spec)
+			if err != nil {
+				return err
 			}
+			_, err = fmt.Print(spec)
+			return err
+		}
+
+		if trekOptions.evaluations {
+			if trekOptions.displayFormat == "" {
+				trekOptions.displayFormat = evaluationsFormat
+			}
+			evaluations, err := trekState.CurrentEvaluations()
+			if err != nil {
+				return err
+			}
+			provider := evaluationsFormatProvider{
+				Evaluations: buildEvaluations(evaluations),
+			}
+			return printDetails(provider)
+		}
+
+		if trekOptions.allocationID != "" {
+			break
 		}
 
 		if trekOptions.taskGroup == "" {
@@ -45,107 +126,307 @@ func runCommand(trekOptions trekOptions) error {
 			provider := jobFormatProvider{
 				TaskGroups: buildTaskGroups(trekState.CurrentTaskGroups()),
 			}
-			trekPrintDetails(os.Stdout, trekOptions.displayFormat, provider)
-
-		} else {
-			// Find task group provided by the user
-			trekState.selectedAllocationGroup = -1
-			for index, tg := range trekState.CurrentTaskGroups() {
-				if *tg.Name == trekOptions.taskGroup {
-					trekState.selectedAllocationGroup = index
-				}
+			return printDetails(provider)
+		}
+
+		if err := selectTaskGroupByName(trekState, trekOptions.taskGroup); err != nil {
+			return err
+		}
+
+		allocations, err := trekState.CurrentAllocations()
+		if err != nil {
+			return err
+		}
+
+		// No allocation provided by the user, display all of them
+		if trekOptions.allocationIndex == -1 {
+
+			if trekOptions.displayFormat == "" {
+				trekOptions.displayFormat = allocationsFormat
+			}
+
+			provider := taskGroupFormatProvider{
+				Allocations: buildAllocations(allocations),
 			}
+			return printDetails(provider)
+		}
+
+		trekState.selectedAllocationIndex = trekOptions.allocationIndex
 
-			if trekState.selectedAllocationGroup < 0 || trekState.selectedAllocationGroup > len(trekState.CurrentTaskGroups())-1 {
-
-				// No such task group found, display available ones
-				fmt.Printf("Unknown task group.  Available task groups:\n")
-				for _, tg := range trekState.CurrentTaskGroups() {
-					fmt.Printf("* %s\n", *tg.Name)
-				}
-
-			} else {
-				// No allocation provided by the user, display all of them
-				if trekOptions.allocationIndex == -1 {
-
-					if trekOptions.displayFormat == "" {
-						trekOptions.displayFormat = allocationsFormat
-					}
-
-					provider := taskGroupFormatProvider{
-						Allocations: buildAllocations(trekState.CurrentAllocations()),
-					}
-					trekPrintDetails(os.Stdout, trekOptions.displayFormat, provider)
-
-				} else {
-					trekState.selectedAllocationIndex = trekOptions.allocationIndex
-
-					if trekOptions.allocationIndex < 0 || trekOptions.allocationIndex > len(trekState.CurrentAllocations())-1 {
-
-						// out of bounds, show existing ones
-						fmt.Printf("Allocation index %d out-of-bounds.  Valid indices:\n", trekOptions.allocationIndex)
-						for index, alloc := range trekState.CurrentAllocations() {
-							fmt.Printf("(%d) %s\n", index, alloc.Name)
-						}
-
-					} else {
-
-						// Allocation found, no task provided by user
-						if trekOptions.taskName == "" {
-
-							if trekOptions.displayFormat == "" {
-								trekOptions.displayFormat = allocationDetailsFormat
-							}
-
-							alloc, _ := trekState.CurrentAllocation()
-							provider := allocationFormatProvider{
-								IP:    alloc.IP(),
-								Tasks: buildTasks(trekState.Tasks()),
-							}
-							trekPrintDetails(os.Stdout, trekOptions.displayFormat, provider)
-
-						} else {
-
-							// Find task
-							trekState.selectedTask = -1
-							for index, task := range trekState.Tasks() {
-								if task.Name == trekOptions.taskName {
-									trekState.selectedTask = index
-								}
-							}
-
-							// No task? Show all of them
-							if trekState.selectedTask == -1 {
-								fmt.Printf("Task %s not found.  Available tasks:\n", trekOptions.taskName)
-								for _, task := range trekState.Tasks() {
-									fmt.Printf("* %s\n", task.Name)
-								}
-							} else {
-
-								if trekOptions.displayFormat == "" {
-									trekOptions.displayFormat = taskDetailsFormat
-								}
-
-								alloc, _ := trekState.CurrentAllocation()
-								task := trekState.CurrentTask()
-
-								provider := taskFormatProvider{
-									Task:        trekTask{Name: task.Name, Driver: task.Driver, Config: task.Config},
-									Node:        trekNode{Name: alloc.node.Name, IP: alloc.IP()},
-									Network:     buildNetwork(alloc.allocation.TaskResources[task.Name].Networks),
-									Environment: buildEnv(task.Env),
-								}
-								trekPrintDetails(os.Stdout, trekOptions.displayFormat, provider)
-							}
-						}
-					}
-				}
+		if trekOptions.allocationIndex < 0 || trekOptions.allocationIndex > len(allocations)-1 {
+			// out of bounds, show existing ones
+			names := make([]string, 0)
+			for index, alloc := range allocations {
+				names = append(names, fmt.Sprintf("(%d) %s", index, alloc.Name))
 			}
+			return candidatesError(NotFoundError, fmt.Sprintf("allocation index %d out-of-bounds.  Valid indices:", trekOptions.allocationIndex), names)
 		}
 
 	default:
-		fmt.Printf("Unknown mode: %s.  Exiting.\n", trekOptions.trekMode)
-		os.Exit(1)
+		return newTrekError(GenericError, nil, "unknown mode: %s", trekOptions.trekMode)
+	}
+
+	alloc, err := trekState.CurrentAllocation()
+	if err != nil {
+		return err
+	}
+
+	// Files of the allocation directory were requested
+	if trekOptions.taskName == "" && trekOptions.browsesFiles() {
+		return printFiles(trekState, alloc, "", trekOptions)
+	}
+
+	// Resource usage of all the tasks of the allocation was requested
+	if trekOptions.taskName == "" && trekOptions.showStats {
+		return printStats(trekState, alloc, "", trekOptions)
+	}
+
+	// Allocation found, no task provided by user
+	if trekOptions.taskName == "" && !trekOptions.showLogs && trekOptions.execCommand == "" {
+
+		if trekOptions.displayFormat == "" {
+			trekOptions.displayFormat = allocationDetailsFormat
+		}
+
+		provider := allocationFormatProvider{
+			IP:    alloc.IP(),
+			Tasks: buildTasks(trekState.Tasks()),
+		}
+		return printDetails(provider)
+	}
+
+	// Find task
+	trekState.selectedTask = -1
+	for index, task := range trekState.Tasks() {
+		if task.Name == trekOptions.taskName {
+			trekState.selectedTask = index
+		}
+	}
+
+	// Logs of (or exec into) the only task of the allocation were requested
+	if trekOptions.taskName == "" && len(trekState.Tasks()) == 1 {
+		trekState.selectedTask = 0
+	}
+
+	// No task? Show all of them
+	if trekState.selectedTask == -1 {
+		names := make([]string, 0)
+		for _, task := range trekState.Tasks() {
+			names = append(names, task.Name)
+		}
+		return candidatesError(NotFoundError, fmt.Sprintf("task %s not found.  Available tasks:", trekOptions.taskName), names)
+	}
+
+	task, err := trekState.CurrentTask()
+	if err != nil {
+		return err
+	}
+
+	if trekOptions.showStats {
+		return printStats(trekState, alloc, task.Name, trekOptions)
+	}
+
+	if trekOptions.browsesFiles() {
+		return printFiles(trekState, alloc, task.Name, trekOptions)
+	}
+
+	if trekOptions.showLogs {
+		return streamLogs(trekState.client, &alloc.allocation, task.Name, trekOptions.logType(), trekOptions.follow, trekOptions.tail, os.Stdout, nil)
+	}
+
+	if trekOptions.execCommand != "" {
+		if trekState.ReadOnly() {
+			return trekState.readOnlyError()
+		}
+		command, err := parseExecCommand(trekOptions.execCommand)
+		if err != nil {
+			return err
+		}
+		code, err := execTask(trekState.client, &alloc.allocation, task.Name, command)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return commandExitError{code: code}
+		}
+		return nil
+	}
+
+	if trekOptions.displayFormat == "" {
+		trekOptions.displayFormat = taskDetailsFormat
+	}
+
+	provider, err := buildTaskDetails(alloc, task)
+	if err != nil {
+		return err
+	}
+	return printDetails(provider)
+}
+
+func selectJobByName(trekState *trekStateType, name string) error {
+	jobs, err := trekState.Jobs()
+	if err != nil {
+		return err
+	}
+
+	// Find job provided by the user
+	matches := make([]string, 0)
+	for index, job := range jobs {
+		if job.Name == name {
+			trekState.selectedJob = index
+			matches = append(matches, job.ID)
+		}
+	}
+
+	if len(matches) == 0 {
+		names := make([]string, 0)
+		for _, job := range jobs {
+			names = append(names, job.Name)
+		}
+		return candidatesError(NotFoundError, fmt.Sprintf("job %s not found.  Available jobs:", name), names)
+	}
+	if len(matches) > 1 {
+		return candidatesError(AmbiguousSelectionError, fmt.Sprintf("job name %s is ambiguous.  Matching job IDs:", name), matches)
+	}
+
+	return trekState.LoadCurrentJob()
+}
+
+func selectTaskGroupByName(trekState *trekStateType, name string) error {
+	// Find task group provided by the user
+	trekState.selectedAllocationGroup = -1
+	for index, tg := range trekState.CurrentTaskGroups() {
+		if *tg.Name == name {
+			trekState.selectedAllocationGroup = index
+		}
+	}
+
+	if trekState.selectedAllocationGroup < 0 {
+		// No such task group found, display available ones
+		names := make([]string, 0)
+		for _, tg := range trekState.CurrentTaskGroups() {
+			names = append(names, *tg.Name)
+		}
+		return candidatesError(NotFoundError, fmt.Sprintf("unknown task group %s.  Available task groups:", name), names)
 	}
 	return nil
 }
+
+// selectAllocationByID selects an allocation given its full or short ID, along
+// with its job and task group
+func selectAllocationByID(trekState *trekStateType, trekOptions trekOptions) error {
+	prefix := strings.ToLower(trekOptions.allocationID)
+
+	// The API only accepts prefixes of even length
+	queryPrefix := prefix
+	if len(queryPrefix)%2 == 1 {
+		queryPrefix = queryPrefix[:len(queryPrefix)-1]
+	}
+
+	stubs, _, err := trekState.client.Allocations().List(&nomad.QueryOptions{Prefix: queryPrefix})
+	if err != nil {
+		return apiError(err, "cannot list allocations")
+	}
+
+	matches := make([]*nomad.AllocationListStub, 0)
+	for _, stub := range stubs {
+		if strings.HasPrefix(stub.ID, prefix) {
+			matches = append(matches, stub)
+		}
+	}
+
+	if len(matches) == 0 {
+		return newTrekError(NotFoundError, nil, "no allocation with ID prefix %s", trekOptions.allocationID)
+	}
+	if len(matches) > 1 {
+		candidates := make([]string, 0)
+		for _, stub := range matches {
+			candidates = append(candidates, fmt.Sprintf("%s %s (%s)", stub.ID, stub.Name, stub.ClientStatus))
+		}
+		return candidatesError(AmbiguousSelectionError, fmt.Sprintf("allocation ID prefix %s is ambiguous.  Matching allocations:", trekOptions.allocationID), candidates)
+	}
+	stub := matches[0]
+
+	jobs, err := trekState.Jobs()
+	if err != nil {
+		return err
+	}
+
+	trekState.selectedJob = -1
+	for index, job := range jobs {
+		if job.ID == stub.JobID {
+			trekState.selectedJob = index
+		}
+	}
+	if err := trekState.LoadCurrentJob(); err != nil {
+		return err
+	}
+	if trekOptions.jobID != "" && *trekState.CurrentJob().Name != trekOptions.jobID {
+		return newTrekError(NotFoundError, nil, "allocation %s does not belong to job %s", stub.ID, trekOptions.jobID)
+	}
+
+	if trekOptions.taskGroup != "" && stub.TaskGroup != trekOptions.taskGroup {
+		return newTrekError(NotFoundError, nil, "allocation %s does not belong to task group %s", stub.ID, trekOptions.taskGroup)
+	}
+	if err := selectTaskGroupByName(trekState, stub.TaskGroup); err != nil {
+		return err
+	}
+
+	alloc, _, err := trekState.client.Allocations().Info(stub.ID, &nomad.QueryOptions{})
+	if err != nil {
+		return apiError(err, "cannot fetch allocation %s", stub.ID)
+	}
+	trekState.foundAllocations = []nomad.Allocation{*alloc}
+	trekState.selectedAllocationIndex = 0
+	return nil
+}
+
+// printFiles lists a directory or prints a file of an allocation, relative to
+// the directory of the given task if any
+func printFiles(trekState *trekStateType, alloc allocation, task string, trekOptions trekOptions) error {
+	if trekOptions.fsCat != "" {
+		return catFile(trekState.client, &alloc.allocation, allocationPath(task, trekOptions.fsCat), os.Stdout)
+	}
+
+	dir := allocationPath(task, trekOptions.fsList)
+	files, err := listFiles(trekState.client, &alloc.allocation, dir)
+	if err != nil {
+		return err
+	}
+
+	if trekOptions.displayFormat == "" {
+		trekOptions.displayFormat = filesListFormat
+	}
+	provider := filesFormatProvider{
+		Path:  dir,
+		Files: buildFiles(files),
+	}
+	return trekPrintOutput(os.Stdout, trekOptions.outputFormat, trekOptions.displayFormat, provider)
+}
+
+// printStats prints the resource usage of the tasks of an allocation (or of
+// the given task), sampling it again at the requested interval if any
+func printStats(trekState *trekStateType, alloc allocation, task string, trekOptions trekOptions) error {
+	if trekOptions.displayFormat == "" {
+		trekOptions.displayFormat = statsFormat
+	}
+
+	for {
+		usage, err := fetchStats(trekState.client, &alloc.allocation)
+		if err != nil {
+			return err
+		}
+		provider := statsFormatProvider{
+			Timestamp: time.Unix(0, usage.Timestamp),
+			Tasks:     buildTaskStats(usage, task),
+		}
+		if err := trekPrintOutput(os.Stdout, trekOptions.outputFormat, trekOptions.displayFormat, provider); err != nil {
+			return err
+		}
+
+		if trekOptions.statsInterval <= 0 {
+			return nil
+		}
+		time.Sleep(trekOptions.statsInterval)
+	}
+}
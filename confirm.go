@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+)
+
+const confirmViewName = "confirm"
+
+// confirmCallback is called once the user confirms an action
+type confirmCallback func(g *gocui.Gui, trekState *trekStateType) error
+
+type confirmState struct {
+	returnView string
+	onConfirm  confirmCallback
+}
+
+// openConfirm asks the user to confirm an action before running onConfirm.
+// Only `y` confirms, any of `n`, Esc or Enter cancels.
+func openConfirm(g *gocui.Gui, v *gocui.View, trekState *trekStateType, question string, onConfirm confirmCallback) error {
+	maxX, maxY := g.Size()
+	view, err := g.SetView(confirmViewName, maxX/2-30, maxY/2, maxX/2+30, maxY/2+2)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Title = "Confirm"
+	view.Wrap = true
+	view.Clear()
+	fmt.Fprintf(view, "%s [y/N]", question)
+
+	trekState.confirm = &confirmState{returnView: v.Name(), onConfirm: onConfirm}
+	_, err = g.SetCurrentView(confirmViewName)
+	return err
+}
+
+func closeConfirm(g *gocui.Gui, trekState *trekStateType) (*confirmState, error) {
+	confirm := trekState.confirm
+	trekState.confirm = nil
+
+	if err := g.DeleteView(confirmViewName); err != nil {
+		return nil, err
+	}
+	if confirm != nil {
+		if _, err := g.SetCurrentView(confirm.returnView); err != nil {
+			return nil, err
+		}
+	}
+	return confirm, nil
+}
+
+func acceptConfirm(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	confirm, err := closeConfirm(g, trekState)
+	if err != nil || confirm == nil {
+		return err
+	}
+	return confirm.onConfirm(g, trekState)
+}
+
+func rejectConfirm(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	_, err := closeConfirm(g, trekState)
+	return err
+}
+
+// confirmIfRequired runs an action changing the cluster right away, unless the
+// environment requires a confirmation first
+func confirmIfRequired(g *gocui.Gui, v *gocui.View, trekState *trekStateType, question string, action confirmCallback) error {
+	if trekState.connectedEnvironment.RequireConfirmation {
+		return openConfirm(g, v, trekState, question, action)
+	}
+	return action(g, trekState)
+}
@@ -5,6 +5,9 @@ const (
 	allocationsFormat       = `{{range .Allocations}}* {{.Name}}{{println}}{{end}}`
 	allocationDetailsFormat = `{{range $index, $task := .Tasks}}({{$index}}) {{$task.Name}}{{println}}{{end}}`
 	taskGroupsListFormat    = `{{range .TaskGroups}}* {{.Name}}{{println}}{{end}}`
+	nodesListFormat         = `{{range .Nodes}}* {{.Name}} ({{.Status}}, {{.SchedulingEligibility}}{{if .Drain}}, draining{{end}}){{println}}{{end}}`
+	statsFormat             = `{{range .Tasks}}{{$.Timestamp.Format "15:04:05"}} {{.Name}}: cpu {{printf "%.1f" .CPUPercent}}% ({{printf "%.0f" .CPUTotalTicks}} MHz), rss {{Bytes .MemoryRSS}}, throttled {{.ThrottledPeriods}} periods{{println}}{{end}}`
+	filesListFormat         = `{{range .Files}}{{printf "%-11s %10d" .FileMode .Size}} {{.Name}}{{if .IsDir}}/{{end}}{{println}}{{end}}`
 	taskDetailsFormat       = `{{- "" -}}
 * Name: {{ .Task.Name }}
 * Node Name: {{ .Node.Name }}
@@ -31,5 +34,113 @@ const (
 		{{"  * "}}{{.Number}} ({{.Name}}){{println}}
 	{{- end}}
 {{- end -}}
+{{- "" -}}`
+	nodeDetailsFormat = `{{- "" -}}
+* ID: {{ .ID }}
+* Name: {{ .Name }}
+* Address: {{ .Address }}
+* Datacenter: {{ .Datacenter }}
+* Node Class: {{ .NodeClass }}
+* Status: {{ .Status }}
+* Eligibility: {{ .SchedulingEligibility }}
+* Drain: {{ .Drain }}
+* Resources: {{ .Resources.CPU }} MHz, {{ .Resources.MemoryMB }} MB memory, {{ .Resources.DiskMB }} MB disk{{println}}
+{{- if .Drivers -}}
+* Drivers:{{println}}
+	{{- range .Drivers -}}
+		{{"  * "}}{{.Name}}{{if not .Detected}} (undetected){{else if not .Healthy}} (unhealthy){{end}}{{println}}
+	{{- end -}}
+{{- end -}}
+{{- if .Allocations -}}
+* Allocations:{{println}}
+	{{- range .Allocations -}}
+		{{"  * "}}{{.ID}} {{.Name}} ({{.ClientStatus}}/{{.DesiredStatus}}){{println}}
+	{{- end -}}
+{{- end -}}
+{{- if .Meta -}}
+* Meta:{{println}}
+	{{- range $key, $value := .Meta -}}
+		{{"  * "}}{{$key}}: {{$value}}{{println}}
+	{{- end -}}
+{{- end -}}
+{{- if .Attributes -}}
+* Attributes:{{println}}
+	{{- range $key, $value := .Attributes -}}
+		{{"  * "}}{{$key}}: {{$value}}{{println}}
+	{{- end -}}
+{{- end -}}
+{{- "" -}}`
+	deploymentDetailsFormat = `{{- "" -}}
+* ID: {{ .ID }}
+* Job: {{ .JobID }} (version {{ .JobVersion }})
+* Status: {{ .Status }}
+* Description: {{ .StatusDescription }}{{println}}
+{{- if .TaskGroups -}}
+* Task Groups:{{println}}
+	{{- range .TaskGroups -}}
+		{{"  * "}}{{.Name}}: desired {{.Desired}}, placed {{.Placed}}, healthy {{.Healthy}}, unhealthy {{.Unhealthy}}{{println}}
+		{{- if .DesiredCanaries -}}
+			{{"    * "}}canaries: {{.PlacedCanaries}}/{{.DesiredCanaries}}{{if .Promoted}} (promoted){{end}}{{println}}
+		{{- end -}}
+		{{- if .AutoRevert -}}
+			{{"    * "}}auto revert{{println}}
+		{{- end -}}
+		{{- if not .RequireProgressBy.IsZero -}}
+			{{"    * "}}progress deadline: {{.RequireProgressBy.Format "2006-01-02 15:04:05"}}{{println}}
+		{{- end -}}
+	{{- end -}}
+{{- end -}}
+{{- "" -}}`
+	evaluationsFormat = `{{- "" -}}
+{{- range .Evaluations -}}
+* {{ .ID }} {{ .Status }} (triggered by {{ .TriggeredBy }}, {{ .CreateTime.Format "2006-01-02 15:04:05" }}){{println}}
+	{{- if .StatusDescription -}}
+		{{"  * "}}{{.StatusDescription}}{{println}}
+	{{- end -}}
+	{{- range .FailedPlacements -}}
+		{{"  * "}}task group {{.TaskGroup}}: failed to place {{.Failed}}, {{.NodesEvaluated}} nodes evaluated{{println}}
+		{{- range .Reasons -}}
+			{{"    * "}}{{.}}{{println}}
+		{{- end -}}
+	{{- end -}}
+{{- end -}}
+{{- "" -}}`
+	evaluationDetailsFormat = `{{- "" -}}
+* ID: {{ .ID }}
+* Job: {{ .JobID }} (modify index {{ .JobModifyIndex }})
+* Type: {{ .Type }}
+* Status: {{ .Status }}{{if .StatusDescription}} ({{ .StatusDescription }}){{end}}
+* Triggered By: {{ .TriggeredBy }}
+* Created: {{ .CreateTime.Format "2006-01-02 15:04:05" }}{{println}}
+{{- if .DeploymentID -}}
+* Deployment: {{ .DeploymentID }}{{println}}
+{{- end -}}
+{{- if .NodeID -}}
+* Node: {{ .NodeID }}{{println}}
+{{- end -}}
+{{- if .PreviousEval -}}
+* Previous Evaluation: {{ .PreviousEval }}{{println}}
+{{- end -}}
+{{- if .NextEval -}}
+* Next Evaluation: {{ .NextEval }}{{println}}
+{{- end -}}
+{{- if .BlockedEval -}}
+* Blocked Evaluation: {{ .BlockedEval }}{{println}}
+{{- end -}}
+{{- if .QueuedAllocations -}}
+* Queued Allocations:{{println}}
+	{{- range $group, $queued := .QueuedAllocations -}}
+		{{"  * "}}{{$group}}: {{$queued}}{{println}}
+	{{- end -}}
+{{- end -}}
+{{- if .FailedPlacements -}}
+* Placement Failures:{{println}}
+	{{- range .FailedPlacements -}}
+		{{"  * "}}{{.TaskGroup}}: failed to place {{.Failed}}, {{.NodesEvaluated}} nodes evaluated, {{.NodesFiltered}} filtered, {{.NodesExhausted}} exhausted{{println}}
+		{{- range .Reasons -}}
+			{{"    * "}}{{.}}{{println}}
+		{{- end -}}
+	{{- end -}}
+{{- end -}}
 {{- "" -}}`
 )
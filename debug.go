@@ -0,0 +1,496 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	inspectorViewName = "Inspector"
+
+	// requestLogSize bounds how many requests the UI remembers
+	requestLogSize = 500
+
+	// inspectorRequests bounds how many requests the inspector lists
+	inspectorRequests = 20
+)
+
+// apiRequest is an HTTP request made to the Nomad API
+type apiRequest struct {
+	method  string
+	url     *url.URL
+	status  int
+	err     error
+	started time.Time
+	// latency is the time it took to get the headers of the response: for
+	// blocking queries, it includes the time spent waiting for a change
+	latency time.Duration
+}
+
+func (request apiRequest) String() string {
+	path := request.url.Path
+	if request.url.RawQuery != "" {
+		path += "?" + request.url.RawQuery
+	}
+	result := fmt.Sprint(request.status)
+	if request.err != nil {
+		result = request.err.Error()
+	}
+	return fmt.Sprintf("%s %s %s %s %s", request.started.Format("15:04:05"), request.method, path, result,
+		request.latency.Round(time.Microsecond))
+}
+
+// requestLog remembers the latest requests made to the Nomad API.  Watchers
+// query the API in the background, hence the lock.
+type requestLog struct {
+	lock     sync.Mutex
+	requests []apiRequest
+}
+
+func (log *requestLog) record(request apiRequest) {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	log.requests = append(log.requests, request)
+	if len(log.requests) > requestLogSize {
+		log.requests = log.requests[len(log.requests)-requestLogSize:]
+	}
+}
+
+// about lists the latest requests whose path names the given object, newest
+// first
+func (log *requestLog) about(id string, limit int) []apiRequest {
+	log.lock.Lock()
+	defer log.lock.Unlock()
+
+	found := make([]apiRequest, 0)
+	for index := len(log.requests) - 1; index >= 0 && len(found) < limit; index-- {
+		request := log.requests[index]
+		if strings.Contains(request.url.Path+"/", "/"+id+"/") {
+			found = append(found, request)
+		}
+	}
+	return found
+}
+
+// recordingTransport records the requests going through an HTTP transport
+type recordingTransport struct {
+	next http.RoundTripper
+	log  *requestLog
+}
+
+func (transport *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	started := time.Now()
+	response, err := transport.next.RoundTrip(request)
+
+	recorded := apiRequest{method: request.Method, url: request.URL, err: err, started: started, latency: time.Since(started)}
+	if response != nil {
+		recorded.status = response.StatusCode
+	}
+	transport.log.record(recorded)
+	return response, err
+}
+
+// recordingHTTPClient builds an HTTP client configured like the default one of
+// the Nomad API, recording its requests.  The API can't reach nodes directly
+// with it (nor open websockets), and goes through the servers instead.
+func recordingHTTPClient(tlsConfig *nomad.TLSConfig, log *requestLog) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	client := &http.Client{Transport: transport}
+	if err := nomad.ConfigureTLS(client, tlsConfig); err != nil {
+		return nil, err
+	}
+
+	client.Transport = &recordingTransport{next: transport, log: log}
+	return client, nil
+}
+
+// inspectNode is a line of the inspector: a value, whose fields, entries or
+// elements are listed when expanded, or a plain text line
+type inspectNode struct {
+	label    string
+	value    reflect.Value
+	text     string
+	parent   *inspectNode
+	children []*inspectNode
+	expanded bool
+}
+
+func newInspectNode(label string, value interface{}) *inspectNode {
+	return &inspectNode{label: label, value: reflect.ValueOf(value)}
+}
+
+// resolved follows the pointers and interfaces of a value
+func (node *inspectNode) resolved() reflect.Value {
+	value := node.value
+	for value.IsValid() && (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func (node *inspectNode) expandable() bool {
+	if node.text != "" {
+		return len(node.children) > 0
+	}
+	value := node.resolved()
+	switch value.Kind() {
+	case reflect.Struct:
+		return len(exportedFields(value.Type())) > 0
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return value.Len() > 0
+	}
+	return false
+}
+
+func exportedFields(structType reflect.Type) []int {
+	fields := make([]int, 0, structType.NumField())
+	for index := 0; index < structType.NumField(); index++ {
+		if structType.Field(index).PkgPath == "" {
+			fields = append(fields, index)
+		}
+	}
+	return fields
+}
+
+// expand lists the children of a node, the first time it's expanded
+func (node *inspectNode) expand() {
+	node.expanded = true
+	if node.children != nil || node.text != "" {
+		return
+	}
+
+	value := node.resolved()
+	switch value.Kind() {
+	case reflect.Struct:
+		for _, index := range exportedFields(value.Type()) {
+			node.addChild(value.Type().Field(index).Name, value.Field(index))
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			node.addChild(fmt.Sprint(key), value.MapIndex(key))
+		}
+	case reflect.Slice, reflect.Array:
+		for index := 0; index < value.Len(); index++ {
+			node.addChild(fmt.Sprintf("[%d]", index), value.Index(index))
+		}
+	}
+}
+
+func (node *inspectNode) addChild(label string, value reflect.Value) {
+	node.children = append(node.children, &inspectNode{label: label, value: value, parent: node})
+}
+
+func (node *inspectNode) depth() int {
+	depth := 0
+	for parent := node.parent; parent != nil; parent = parent.parent {
+		depth++
+	}
+	return depth
+}
+
+// summary describes the value of a node on its line
+func (node *inspectNode) summary() string {
+	if node.text != "" {
+		return node.text
+	}
+	value := node.resolved()
+	if !value.IsValid() {
+		return "nil"
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		if node.expandable() {
+			return value.Type().Name() + " {…}"
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			return "nil"
+		}
+		return fmt.Sprintf("{%d}", value.Len())
+	case reflect.Slice:
+		if value.IsNil() {
+			return "nil"
+		}
+		return fmt.Sprintf("[%d]", value.Len())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]", value.Len())
+	case reflect.String:
+		return fmt.Sprintf("%q", value.String())
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func (node *inspectNode) line() string {
+	marker := "  "
+	if node.expandable() {
+		marker = "▸ "
+		if node.expanded {
+			marker = "▾ "
+		}
+	}
+
+	line := strings.Repeat("  ", node.depth()) + marker
+	if node.label != "" {
+		line += node.label + ": "
+	}
+	if node.expanded && node.text == "" {
+		// the children show the value
+		return strings.TrimSuffix(line, ": ")
+	}
+	return line + node.summary()
+}
+
+// visibleNodes lists the nodes shown by the inspector, in order
+func visibleNodes(nodes []*inspectNode) []*inspectNode {
+	visible := make([]*inspectNode, 0)
+	for _, node := range nodes {
+		visible = append(visible, node)
+		if node.expanded {
+			visible = append(visible, visibleNodes(node.children)...)
+		}
+	}
+	return visible
+}
+
+// inspectorState describes the inspector panel
+type inspectorState struct {
+	returnView string
+	roots      []*inspectNode
+	lines      []*inspectNode
+}
+
+// inspectSelection fetches the object selected in a panel, returning the
+// nodes showing it and the ID naming it in the API paths
+func inspectSelection(trekState *trekStateType, panel string) ([]*inspectNode, string, error) {
+	client := trekState.client
+
+	switch panel {
+	case "Jobs":
+		stub, err := trekState.CurrentJobStub()
+		if err != nil {
+			return nil, "", err
+		}
+		job, _, err := client.Jobs().Info(stub.ID, &nomad.QueryOptions{Namespace: stub.Namespace})
+		if err != nil {
+			return nil, "", apiError(err, "cannot fetch job %s", stub.ID)
+		}
+		return []*inspectNode{newInspectNode("Job "+stub.ID, job)}, stub.ID, nil
+
+	case "Task Groups":
+		job := trekState.CurrentJob()
+		index := trekState.selectedAllocationGroup
+		if index < 0 || index > len(job.TaskGroups)-1 {
+			return nil, "", newTrekError(NotFoundError, nil, "task group not found")
+		}
+		group := job.TaskGroups[index]
+		return []*inspectNode{newInspectNode("TaskGroup "+*group.Name, group)}, *job.ID, nil
+
+	case "Allocations", "Tasks", "Task":
+		if err := trekState.RefreshCurrentAllocation(); err != nil {
+			return nil, "", err
+		}
+		alloc := trekState.foundAllocations[trekState.selectedAllocationIndex]
+		if panel == "Allocations" {
+			return []*inspectNode{newInspectNode("Allocation "+alloc.Name, alloc)}, alloc.ID, nil
+		}
+
+		tasks := trekState.Tasks()
+		if trekState.selectedTask < 0 || trekState.selectedTask > len(tasks)-1 {
+			return nil, "", newTrekError(NotFoundError, nil, "task not found")
+		}
+		task := tasks[trekState.selectedTask]
+		return []*inspectNode{
+			newInspectNode("Task "+task.Name, task),
+			newInspectNode("TaskState", alloc.TaskStates[task.Name]),
+		}, alloc.ID, nil
+
+	case nodesViewName, nodeViewName:
+		stub, err := trekState.CurrentNode()
+		if err != nil {
+			return nil, "", err
+		}
+		node, _, err := client.Nodes().Info(stub.ID, &nomad.QueryOptions{})
+		if err != nil {
+			return nil, "", apiError(err, "cannot fetch node %s", stub.ID)
+		}
+		return []*inspectNode{newInspectNode("Node "+stub.Name, node)}, stub.ID, nil
+
+	case deploymentsViewName, deploymentViewName:
+		deployment, err := trekState.CurrentDeployment()
+		if err != nil {
+			return nil, "", err
+		}
+		deployment, _, err = client.Deployments().Info(deployment.ID, &nomad.QueryOptions{Namespace: deployment.Namespace})
+		if err != nil {
+			return nil, "", apiError(err, "cannot fetch deployment")
+		}
+		return []*inspectNode{newInspectNode("Deployment "+shortID(deployment.ID), deployment)}, deployment.ID, nil
+
+	case evaluationsViewName, evaluationViewName:
+		evaluation, err := trekState.CurrentEvaluation()
+		if err != nil {
+			return nil, "", err
+		}
+		evaluation, _, err = client.Evaluations().Info(evaluation.ID, &nomad.QueryOptions{Namespace: evaluation.Namespace})
+		if err != nil {
+			return nil, "", apiError(err, "cannot fetch evaluation")
+		}
+		return []*inspectNode{newInspectNode("Evaluation "+shortID(evaluation.ID), evaluation)}, evaluation.ID, nil
+	}
+
+	return nil, "", newTrekError(NotFoundError, nil, "nothing to inspect in panel %s", panel)
+}
+
+// requestsNode lists the requests made about an object, with their latency
+func requestsNode(log *requestLog, id string) *inspectNode {
+	requests := log.about(id, inspectorRequests)
+	node := &inspectNode{label: "Requests", text: fmt.Sprintf("%d latest requests about %s", len(requests), id)}
+	for _, request := range requests {
+		node.children = append(node.children, &inspectNode{text: request.String(), parent: node})
+	}
+	return node
+}
+
+// showInspector opens a panel showing the raw API object behind the selection
+// of the focused panel, along with the requests trek made to fetch it.
+// Pressing debug again closes it.
+func showInspector(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	if v == nil {
+		return nil
+	}
+	if v.Name() == inspectorViewName {
+		return closeInspector(g, v, trekState)
+	}
+	if trekState.client == nil {
+		return openPopup(g, v, trekState, "Select a cluster first")
+	}
+
+	roots, id, err := inspectSelection(trekState, v.Name())
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	for _, root := range roots {
+		root.expand()
+	}
+	roots = append(roots, requestsNode(trekState.requests, id))
+
+	maxX, maxY := g.Size()
+	bounds := getBounds(maxX, maxY, 0, 1, 5)
+	view, err := g.SetView(inspectorViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+	view.Title = fmt.Sprintf("Inspector: %s (enter: expand/collapse)", v.Name())
+	view.SetOrigin(0, 0)
+	view.SetCursor(0, 0)
+
+	trekState.inspector = &inspectorState{returnView: v.Name(), roots: roots}
+	renderInspector(view, trekState)
+
+	_, err = g.SetCurrentView(inspectorViewName)
+	return err
+}
+
+func renderInspector(v *gocui.View, trekState *trekStateType) {
+	inspector := trekState.inspector
+	inspector.lines = visibleNodes(inspector.roots)
+
+	v.Clear()
+	for _, node := range inspector.lines {
+		fmt.Fprintln(v, node.line())
+	}
+}
+
+// selectedNode returns the node under the cursor of the inspector
+func selectedNode(v *gocui.View, trekState *trekStateType) *inspectNode {
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	lines := trekState.inspector.lines
+	if oy+cy > len(lines)-1 {
+		return nil
+	}
+	return lines[oy+cy]
+}
+
+// toggleNode expands the node under the cursor, or collapses it
+func toggleNode(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	node := selectedNode(v, trekState)
+	if node == nil || !node.expandable() {
+		return nil
+	}
+	if node.expanded {
+		node.expanded = false
+	} else {
+		node.expand()
+	}
+	renderInspector(v, trekState)
+	return nil
+}
+
+// collapseNode collapses the node under the cursor, or its parent
+func collapseNode(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	node := selectedNode(v, trekState)
+	if node == nil {
+		return nil
+	}
+	if !node.expanded || !node.expandable() {
+		node = node.parent
+	}
+	if node == nil {
+		return nil
+	}
+	node.expanded = false
+	renderInspector(v, trekState)
+
+	for line, visible := range trekState.inspector.lines {
+		if visible == node {
+			scrollTo(v, line)
+		}
+	}
+	return nil
+}
+
+func closeInspector(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	returnView := trekState.inspector.returnView
+	trekState.inspector = nil
+	if err := g.DeleteView(inspectorViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView(returnView)
+	return err
+}
+
+// setInspectorCursor does nothing: the inspector reads its cursor when needed
+func setInspectorCursor(trekState *trekStateType, position cursorPosition) {}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	deploymentsViewName = "Deployments"
+	deploymentViewName  = "Deployment"
+)
+
+// CurrentDeployments lists the deployments of the selected job, newest first
+func (trekState *trekStateType) CurrentDeployments() ([]*nomad.Deployment, error) {
+	job := trekState.CurrentJob()
+	options := &nomad.QueryOptions{}
+	if job.Namespace != nil {
+		options.Namespace = *job.Namespace
+	}
+
+	deployments, _, err := trekState.client.Jobs().Deployments(*job.ID, false, options)
+	if err != nil {
+		return nil, apiError(err, "cannot list deployments of job %s", *job.ID)
+	}
+
+	sort.SliceStable(deployments, func(i, j int) bool { return deployments[i].CreateIndex > deployments[j].CreateIndex })
+	trekState.deployments = deployments
+	return deployments, nil
+}
+
+func (trekState *trekStateType) CurrentDeployment() (*nomad.Deployment, error) {
+	index := trekState.selectedDeployment
+	if index < 0 || index > len(trekState.deployments)-1 {
+		return nil, newTrekError(NotFoundError, nil, "deployment not found")
+	}
+	return trekState.deployments[index], nil
+}
+
+// deploymentHealth sums the healthy and desired allocations of a deployment
+func deploymentHealth(deployment *nomad.Deployment) (int, int) {
+	var healthy, desired int
+	for _, state := range deployment.TaskGroups {
+		healthy += state.HealthyAllocs
+		desired += state.DesiredTotal
+	}
+	return healthy, desired
+}
+
+func selectDeployments(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	_, err := g.View(deploymentsViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(deploymentsViewName)
+	}
+
+	trekState.trackView(selectDeployments)
+
+	client := trekState.client
+	job := trekState.CurrentJob()
+	trekState.startWatch(g, panelWatch{
+		viewName: deploymentsViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			if job.Namespace != nil {
+				options.Namespace = *job.Namespace
+			}
+			_, meta, err := client.Jobs().Deployments(*job.ID, false, options)
+			return meta, err
+		},
+		render:    renderDeployments,
+		selection: setSelectedDeployment,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    deploymentsViewName,
+			foregroundAfterCreation: true,
+			panelNum:                0,
+			panelsTotal:             1,
+			margin:                  5,
+			handler:                 renderDeployments,
+		},
+		trekState,
+	)
+}
+
+func renderDeployments(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+	view.Title = fmt.Sprintf("Deployments: %s", *trekState.CurrentJob().ID)
+
+	deployments, err := trekState.CurrentDeployments()
+	if err != nil {
+		fmt.Fprintln(view, err)
+		return nil
+	}
+
+	if len(deployments) == 0 {
+		fmt.Fprintln(view, "no deployment")
+	}
+	for _, deployment := range deployments {
+		healthy, desired := deploymentHealth(deployment)
+		fmt.Fprintf(view, "%s v%d %s (%d/%d healthy) %s\n",
+			shortID(deployment.ID), deployment.JobVersion, trekState.theme.statusText(deployment.Status), healthy, desired, deployment.StatusDescription)
+	}
+
+	return nil
+}
+
+func selectDeployment(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	deployment, err := trekState.CurrentDeployment()
+	if err != nil {
+		return nil
+	}
+
+	_, err = g.View(deploymentViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(deploymentViewName)
+	}
+
+	trekState.trackView(selectDeployment)
+
+	client := trekState.client
+	deploymentID := deployment.ID
+	namespace := deployment.Namespace
+	trekState.startWatch(g, panelWatch{
+		viewName: deploymentViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			options.Namespace = namespace
+			_, meta, err := client.Deployments().Info(deploymentID, options)
+			return meta, err
+		},
+		render: renderDeployment,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    deploymentViewName,
+			foregroundAfterCreation: true,
+			panelNum:                0,
+			panelsTotal:             1,
+			margin:                  10,
+			handler:                 renderDeployment,
+		},
+		trekState,
+	)
+}
+
+func renderDeployment(view *gocui.View, trekState *trekStateType) error {
+	view.Editable = false
+	view.Wrap = false
+
+	deployment, err := trekState.CurrentDeployment()
+	if err == nil {
+		deployment, _, err = trekState.client.Deployments().Info(deployment.ID, &nomad.QueryOptions{Namespace: deployment.Namespace})
+		if err != nil {
+			err = apiError(err, "cannot fetch deployment")
+		}
+	}
+	if err == nil {
+		trekState.deployments[trekState.selectedDeployment] = deployment
+		view.Title = fmt.Sprintf("Deployment: %s (p: promote, f: fail, s: pause/resume)", shortID(deployment.ID))
+		err = trekPrintDetails(view, deploymentDetailsFormat, buildDeployment(deployment))
+	}
+	if err != nil {
+		fmt.Fprintln(view, err)
+	}
+	return nil
+}
+
+func setSelectedDeployment(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedDeployment = position.y
+}
+
+// deploymentAction is an update of a deployment (promotion, failure, pause)
+type deploymentAction func(deployments *nomad.Deployments, deployment *nomad.Deployment, options *nomad.WriteOptions) (*nomad.DeploymentUpdateResponse, *nomad.WriteMeta, error)
+
+// updateDeployment runs an action on the selected deployment once the user
+// confirms it, and reports the evaluation it triggered
+func updateDeployment(question string, done string, action deploymentAction) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		deployment, err := trekState.CurrentDeployment()
+		if err != nil {
+			return openPopup(g, v, trekState, err.Error())
+		}
+
+		return openConfirm(g, v, trekState, fmt.Sprintf(question, shortID(deployment.ID)), func(g *gocui.Gui, trekState *trekStateType) error {
+			response, _, err := action(trekState.client.Deployments(), deployment, &nomad.WriteOptions{Namespace: deployment.Namespace})
+			if err != nil {
+				return reportActionError(g, trekState, apiError(err, "cannot update deployment %s", shortID(deployment.ID)))
+			}
+			return reportEvaluation(g, trekState, fmt.Sprintf(done, shortID(deployment.ID)), response.EvalID)
+		})
+	}
+}
+
+var promoteDeployment = updateDeployment("Promote the canaries of deployment %s?", "Deployment %s promoted",
+	func(deployments *nomad.Deployments, deployment *nomad.Deployment, options *nomad.WriteOptions) (*nomad.DeploymentUpdateResponse, *nomad.WriteMeta, error) {
+		return deployments.PromoteAll(deployment.ID, options)
+	})
+
+var failDeployment = updateDeployment("Mark deployment %s as failed?", "Deployment %s failed",
+	func(deployments *nomad.Deployments, deployment *nomad.Deployment, options *nomad.WriteOptions) (*nomad.DeploymentUpdateResponse, *nomad.WriteMeta, error) {
+		return deployments.Fail(deployment.ID, options)
+	})
+
+// toggleDeploymentPause pauses a running deployment, or resumes a paused one
+func toggleDeploymentPause(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	deployment, err := trekState.CurrentDeployment()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+
+	pause := deployment.Status != "paused"
+	question, done := "Pause deployment %s?", "Deployment %s paused"
+	if !pause {
+		question, done = "Resume deployment %s?", "Deployment %s resumed"
+	}
+	return updateDeployment(question, done,
+		func(deployments *nomad.Deployments, deployment *nomad.Deployment, options *nomad.WriteOptions) (*nomad.DeploymentUpdateResponse, *nomad.WriteMeta, error) {
+			return deployments.Pause(deployment.ID, pause, options)
+		})(g, v, trekState)
+}
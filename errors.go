@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// trekErrorKind classifies failures so scripts can tell them apart
+type trekErrorKind int
+
+const (
+	// GenericError is used for failures that don't fit any other kind
+	GenericError trekErrorKind = iota
+
+	// ConnectionError is used when the cluster can't be reached or refuses a request
+	ConnectionError
+
+	// NotFoundError is used when a job, task group, allocation or task doesn't exist
+	NotFoundError
+
+	// AmbiguousSelectionError is used when a selection matches more than one object
+	AmbiguousSelectionError
+
+	// TemplateError is used when a display format can't be parsed or executed
+	TemplateError
+
+	// ReadOnlyError is used when an action would change a read-only cluster
+	ReadOnlyError
+)
+
+// ExitCode returns the process exit code associated to an error kind.  -exec
+// exits with the code of the remote command, so trek's own codes stay clear of
+// the ones commands commonly use: they sit right below the codes shells
+// reserve (126 and up).
+func (kind trekErrorKind) ExitCode() int {
+	switch kind {
+	case ConnectionError:
+		return 121
+	case NotFoundError:
+		return 122
+	case AmbiguousSelectionError:
+		return 123
+	case TemplateError:
+		return 124
+	case ReadOnlyError:
+		return 125
+	default:
+		return 120
+	}
+}
+
+type trekError struct {
+	kind    trekErrorKind
+	message string
+	cause   error
+}
+
+func (err *trekError) Error() string {
+	if err.cause != nil {
+		return fmt.Sprintf("%s: %s", err.message, err.cause)
+	}
+	return err.message
+}
+
+func newTrekError(kind trekErrorKind, cause error, format string, args ...interface{}) error {
+	return &trekError{kind: kind, message: fmt.Sprintf(format, args...), cause: cause}
+}
+
+// apiError wraps an error returned by the nomad API, telling apart missing
+// objects from connectivity problems
+func apiError(cause error, format string, args ...interface{}) error {
+	if strings.Contains(cause.Error(), "Unexpected response code: 404") {
+		return newTrekError(NotFoundError, cause, format, args...)
+	}
+	return newTrekError(ConnectionError, cause, format, args...)
+}
+
+// isNotFound tells whether an error reports a missing object
+func isNotFound(err error) bool {
+	trekErr, ok := err.(*trekError)
+	return ok && trekErr.kind == NotFoundError
+}
+
+// candidatesError lists the available choices when a selection doesn't match
+// exactly one of them
+func candidatesError(kind trekErrorKind, header string, candidates []string) error {
+	var message strings.Builder
+	message.WriteString(header)
+	for _, candidate := range candidates {
+		fmt.Fprintf(&message, "\n* %s", candidate)
+	}
+	return &trekError{kind: kind, message: message.String()}
+}
+
+// commandExitError reports a command run with -exec exiting with a non-zero
+// code, which trek passes through as its own exit code
+type commandExitError struct {
+	code int
+}
+
+func (err commandExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", err.code)
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(commandExitError); ok {
+		return exitErr.code
+	}
+	if trekErr, ok := err.(*trekError); ok {
+		return trekErr.kind.ExitCode()
+	}
+	return GenericError.ExitCode()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	evaluationsViewName = "Evaluations"
+	evaluationViewName  = "Evaluation"
+)
+
+// CurrentEvaluations lists the evaluations of the selected job, newest first
+func (trekState *trekStateType) CurrentEvaluations() ([]*nomad.Evaluation, error) {
+	job := trekState.CurrentJob()
+	options := &nomad.QueryOptions{}
+	if job.Namespace != nil {
+		options.Namespace = *job.Namespace
+	}
+
+	evaluations, _, err := trekState.client.Jobs().Evaluations(*job.ID, options)
+	if err != nil {
+		return nil, apiError(err, "cannot list evaluations of job %s", *job.ID)
+	}
+
+	sort.SliceStable(evaluations, func(i, j int) bool { return evaluations[i].CreateIndex > evaluations[j].CreateIndex })
+	trekState.evaluations = evaluations
+	return evaluations, nil
+}
+
+func (trekState *trekStateType) CurrentEvaluation() (*nomad.Evaluation, error) {
+	index := trekState.selectedEvaluation
+	if index < 0 || index > len(trekState.evaluations)-1 {
+		return nil, newTrekError(NotFoundError, nil, "evaluation not found")
+	}
+	return trekState.evaluations[index], nil
+}
+
+func selectEvaluations(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	_, err := g.View(evaluationsViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(evaluationsViewName)
+	}
+
+	trekState.trackView(selectEvaluations)
+
+	client := trekState.client
+	job := trekState.CurrentJob()
+	trekState.startWatch(g, panelWatch{
+		viewName: evaluationsViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			if job.Namespace != nil {
+				options.Namespace = *job.Namespace
+			}
+			_, meta, err := client.Jobs().Evaluations(*job.ID, options)
+			return meta, err
+		},
+		render:    renderEvaluations,
+		selection: setSelectedEvaluation,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    evaluationsViewName,
+			foregroundAfterCreation: true,
+			panelNum:                0,
+			panelsTotal:             1,
+			margin:                  5,
+			handler:                 renderEvaluations,
+		},
+		trekState,
+	)
+}
+
+func renderEvaluations(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+	view.Title = fmt.Sprintf("Evaluations: %s", *trekState.CurrentJob().ID)
+
+	evaluations, err := trekState.CurrentEvaluations()
+	if err != nil {
+		fmt.Fprintln(view, err)
+		return nil
+	}
+
+	if len(evaluations) == 0 {
+		fmt.Fprintln(view, "no evaluation")
+	}
+	for _, evaluation := range evaluations {
+		line := fmt.Sprintf("%s %s %s (%s ago)", shortID(evaluation.ID), trekState.theme.statusText(evaluation.Status), evaluation.TriggeredBy,
+			formatAge(time.Unix(0, evaluation.CreateTime)))
+		if len(evaluation.FailedTGAllocs) > 0 {
+			groups := make([]string, 0, len(evaluation.FailedTGAllocs))
+			for group := range evaluation.FailedTGAllocs {
+				groups = append(groups, group)
+			}
+			sort.Strings(groups)
+			line += fmt.Sprintf(" placement failed: %s", strings.Join(groups, ", "))
+		}
+		fmt.Fprintln(view, line)
+	}
+
+	return nil
+}
+
+func selectEvaluation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	evaluation, err := trekState.CurrentEvaluation()
+	if err != nil {
+		return nil
+	}
+
+	_, err = g.View(evaluationViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(evaluationViewName)
+	}
+
+	trekState.trackView(selectEvaluation)
+
+	client := trekState.client
+	evaluationID := evaluation.ID
+	namespace := evaluation.Namespace
+	trekState.startWatch(g, panelWatch{
+		viewName: evaluationViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			options.Namespace = namespace
+			_, meta, err := client.Evaluations().Info(evaluationID, options)
+			return meta, err
+		},
+		render: renderEvaluation,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    evaluationViewName,
+			foregroundAfterCreation: true,
+			panelNum:                0,
+			panelsTotal:             1,
+			margin:                  10,
+			handler:                 renderEvaluation,
+		},
+		trekState,
+	)
+}
+
+func renderEvaluation(view *gocui.View, trekState *trekStateType) error {
+	view.Editable = false
+	view.Wrap = false
+
+	evaluation, err := trekState.CurrentEvaluation()
+	if err == nil {
+		evaluation, _, err = trekState.client.Evaluations().Info(evaluation.ID, &nomad.QueryOptions{Namespace: evaluation.Namespace})
+		if err != nil {
+			err = apiError(err, "cannot fetch evaluation")
+		}
+	}
+	if err == nil {
+		trekState.evaluations[trekState.selectedEvaluation] = evaluation
+		view.Title = fmt.Sprintf("Evaluation: %s", shortID(evaluation.ID))
+		err = trekPrintDetails(view, evaluationDetailsFormat, buildEvaluation(evaluation))
+	}
+	if err != nil {
+		fmt.Fprintln(view, err)
+	}
+	return nil
+}
+
+func setSelectedEvaluation(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedEvaluation = position.y
+}
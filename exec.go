@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+	"golang.org/x/term"
+)
+
+// defaultExecCommand is run when no command is given to exec
+const defaultExecCommand = "/bin/sh"
+
+// errExecSession is returned by the UI main loop to suspend the UI while an
+// exec session runs
+var errExecSession = errors.New("exec session requested")
+
+// execRequest describes the command to run once the UI is suspended
+type execRequest struct {
+	alloc   nomad.Allocation
+	task    string
+	command []string
+	result  string
+}
+
+// parseExecCommand splits a command line into words the way a POSIX shell
+// does, without expanding anything: single quotes keep their content as is,
+// double quotes keep whitespace and only honor backslashes before ", \, $
+// and `, and a backslash outside quotes escapes the next character.
+func parseExecCommand(command string) ([]string, error) {
+	args := make([]string, 0)
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, c := range command {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\\\"$`", c) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if escaped {
+		return nil, newTrekError(GenericError, nil, "invalid command %q: trailing backslash", command)
+	}
+	if quote != 0 {
+		return nil, newTrekError(GenericError, nil, "invalid command %q: unterminated %c quote", command, quote)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	if len(args) == 0 {
+		args = []string{defaultExecCommand}
+	}
+	return args, nil
+}
+
+// execTask runs a command inside a task, attaching the user's terminal to it.
+// The returned exit code is the one of the remote command.
+func execTask(client *nomad.Client, alloc *nomad.Allocation, task string, command []string) (int, error) {
+	stdinFd := int(os.Stdin.Fd())
+	tty := term.IsTerminal(stdinFd)
+
+	var sizeCh chan nomad.TerminalSize
+	if tty {
+		oldState, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return -1, err
+		}
+		defer term.Restore(stdinFd, oldState)
+
+		sizeCh = make(chan nomad.TerminalSize, 1)
+		stopResize := watchTerminalSize(stdinFd, sizeCh)
+		defer stopResize()
+	}
+
+	stdin, stopStdin := cancellableStdin()
+	defer stopStdin()
+
+	code, err := client.Allocations().Exec(context.Background(), alloc, task, tty, command, stdin, os.Stdout, os.Stderr, sizeCh, nil)
+	if err != nil {
+		return code, apiError(err, "cannot exec into task %s", task)
+	}
+	return code, nil
+}
+
+// watchTerminalSize sends the size of the terminal to sizeCh, and again every
+// time it's resized
+func watchTerminalSize(fd int, sizeCh chan nomad.TerminalSize) func() {
+	sendSize := func() {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			return
+		}
+		select {
+		case sizeCh <- nomad.TerminalSize{Width: width, Height: height}:
+		default:
+		}
+	}
+	sendSize()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				sendSize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// cancellableStdin returns a reader on stdin that stops reading when the
+// returned function is called, so that no keystroke meant for the UI is
+// consumed once an exec session is over
+func cancellableStdin() (io.Reader, func()) {
+	fd, err := syscall.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return os.Stdin, func() {}
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return os.Stdin, func() {}
+	}
+
+	stdin := os.NewFile(uintptr(fd), "stdin")
+	return stdin, func() {
+		stdin.SetReadDeadline(time.Now())
+		// Fd puts the descriptor (shared with stdin) back in blocking mode
+		stdin.Fd()
+		stdin.Close()
+	}
+}
+
+// execIntoTask asks for a command to run in the selected task, then suspends
+// the UI to run it
+func execIntoTask(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	alloc, err := trekState.CurrentAllocation()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	currentTask, err := trekState.CurrentTask()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	task := currentTask.Name
+
+	return openPrompt(g, v, trekState, "Exec (default: "+defaultExecCommand+")", func(g *gocui.Gui, trekState *trekStateType, value string) error {
+		command, err := parseExecCommand(value)
+		if err != nil {
+			return reportActionError(g, trekState, err)
+		}
+		question := fmt.Sprintf("Run %s in task %s?", strings.Join(command, " "), task)
+		return confirmIfRequired(g, g.CurrentView(), trekState, question, func(g *gocui.Gui, trekState *trekStateType) error {
+			trekState.pendingExec = &execRequest{
+				alloc:   alloc.allocation,
+				task:    task,
+				command: command,
+			}
+			return errExecSession
+		})
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExecCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"", []string{defaultExecCommand}},
+		{"   ", []string{defaultExecCommand}},
+		{"redis-cli info server", []string{"redis-cli", "info", "server"}},
+		{"  ls \t -l  ", []string{"ls", "-l"}},
+		{`sh -c 'echo "$HOME" | wc -c'`, []string{"sh", "-c", `echo "$HOME" | wc -c`}},
+		{`echo "hello  world" again`, []string{"echo", "hello  world", "again"}},
+		{`echo "a \"quoted\" \$word and \n"`, []string{"echo", `a "quoted" $word and \n`}},
+		{`echo it\'s a\ b`, []string{"echo", "it's", "a b"}},
+		{`echo '' ""`, []string{"echo", "", ""}},
+		{`echo pre'fix'"ed"`, []string{"echo", "prefixed"}},
+	}
+	for _, test := range tests {
+		args, err := parseExecCommand(test.command)
+		if err != nil {
+			t.Errorf("parseExecCommand(%q): unexpected error %v", test.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("parseExecCommand(%q) = %q, expected %q", test.command, args, test.expected)
+		}
+	}
+
+	for _, command := range []string{`echo 'unterminated`, `echo "unterminated`, `echo trailing\`} {
+		if _, err := parseExecCommand(command); err == nil {
+			t.Errorf("parseExecCommand(%q): expected an error", command)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// listFilter narrows a list panel to the lines matching a query
+type listFilter struct {
+	query string
+
+	// items holds, for each line of the panel, the index of the item it shows
+	// in the unfiltered list
+	items []int
+}
+
+func (trekState *trekStateType) listFilter(viewName string) *listFilter {
+	if trekState.filters == nil {
+		trekState.filters = make(map[string]*listFilter)
+	}
+	filter, ok := trekState.filters[viewName]
+	if !ok {
+		filter = &listFilter{}
+		trekState.filters[viewName] = filter
+	}
+	return filter
+}
+
+// filteredItem maps a line of a list panel to the index of the item it shows,
+// or to -1 when the filter hides every item
+func (trekState *trekStateType) filteredItem(viewName string, line int) int {
+	filter, ok := trekState.filters[viewName]
+	if !ok || line < 0 || line > len(filter.items)-1 {
+		if ok && filter.query != "" {
+			return -1
+		}
+		return line
+	}
+	return filter.items[line]
+}
+
+// filteredLine maps an item to the line of the list panel showing it, or to
+// the first line when the item is filtered out
+func (trekState *trekStateType) filteredLine(viewName string, item int) int {
+	filter, ok := trekState.filters[viewName]
+	if !ok {
+		return item
+	}
+	for line, index := range filter.items {
+		if index == item {
+			return line
+		}
+	}
+	return 0
+}
+
+// visibleLines counts the lines of a list panel
+func (trekState *trekStateType) visibleLines(viewName string) int {
+	return len(trekState.listFilter(viewName).items)
+}
+
+// withSelection runs a handler only when the list panel shows at least one
+// item, so that a filter hiding everything doesn't act on a hidden item
+func withSelection(handler uiHandlerWithStateType) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		if trekState.visibleLines(v.Name()) == 0 {
+			return nil
+		}
+		return handler(g, v, trekState)
+	}
+}
+
+// fuzzyMatch tells whether all the characters of the query appear in the
+// line, in order
+func fuzzyMatch(line string, query string) bool {
+	remaining := []rune(query)
+	for _, r := range line {
+		if len(remaining) == 0 {
+			break
+		}
+		if r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
+// renderList prints the lines of a list panel matching its filter: lines
+// containing the query come first, followed by the fuzzy matches
+func renderList(view *gocui.View, trekState *trekStateType, title string, lines []string) {
+	filter := trekState.listFilter(view.Name())
+	filter.items = make([]int, 0, len(lines))
+
+	query := strings.ToLower(filter.query)
+	if query == "" {
+		view.Title = title
+		for index := range lines {
+			filter.items = append(filter.items, index)
+		}
+	} else {
+		view.Title = fmt.Sprintf("%s /%s", title, filter.query)
+		fuzzy := make([]int, 0)
+		for index, line := range lines {
+			line = strings.ToLower(stripColors(line))
+			if strings.Contains(line, query) {
+				filter.items = append(filter.items, index)
+			} else if fuzzyMatch(line, query) {
+				fuzzy = append(fuzzy, index)
+			}
+		}
+		filter.items = append(filter.items, fuzzy...)
+	}
+
+	for _, index := range filter.items {
+		fmt.Fprintln(view, lines[index])
+	}
+}
+
+// filterList narrows a list panel as the user types a query.  Enter keeps the
+// filter, Esc restores the previous one, and an empty query shows everything.
+func filterList(render viewHandlerCallback, selection cursorCallback) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		viewName := v.Name()
+		previous := trekState.listFilter(viewName).query
+
+		apply := func(g *gocui.Gui, trekState *trekStateType, value string) error {
+			trekState.listFilter(viewName).query = value
+			view, err := g.View(viewName)
+			if err != nil {
+				return nil
+			}
+			return redrawView(view, trekState, render, selection)
+		}
+		restore := func(g *gocui.Gui, trekState *trekStateType, value string) error {
+			return apply(g, trekState, previous)
+		}
+
+		return openIncrementalPrompt(g, v, trekState, "Filter", apply, apply, restore)
+	}
+}
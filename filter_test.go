@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		line     string
+		query    string
+		expected bool
+	}{
+		{"web-api", "", true},
+		{"web-api", "web", true},
+		{"web-api", "wbapi", true},
+		{"web-api", "w-i", true},
+		{"web-api", "iaw", false},
+		{"web-api", "web-apis", false},
+		{"", "a", false},
+		{"élan", "én", true},
+	}
+	for _, test := range tests {
+		if matched := fuzzyMatch(test.line, test.query); matched != test.expected {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, expected %v", test.line, test.query, matched, test.expected)
+		}
+	}
+}
+
+// filterState holds a filtered Jobs panel, whose lines show the given items of
+// the whole list
+func filterState(query string, items []int) *trekStateType {
+	return &trekStateType{filters: map[string]*listFilter{"Jobs": {query: query, items: items}}}
+}
+
+func TestFilteredItem(t *testing.T) {
+	tests := []struct {
+		trekState *trekStateType
+		viewName  string
+		line      int
+		expected  int
+	}{
+		{filterState("api", []int{4, 1, 7}), "Jobs", 0, 4},
+		{filterState("api", []int{4, 1, 7}), "Jobs", 2, 7},
+		{filterState("api", []int{4, 1, 7}), "Jobs", 3, -1},
+		{filterState("api", []int{4, 1, 7}), "Jobs", -1, -1},
+		{filterState("api", []int{}), "Jobs", 0, -1},
+		{filterState("", []int{0, 1, 2}), "Jobs", 1, 1},
+		{filterState("", []int{}), "Jobs", 5, 5},
+		// panels never filtered keep their raw lines
+		{filterState("api", []int{4, 1, 7}), "Tasks", 2, 2},
+		{&trekStateType{}, "Jobs", 3, 3},
+	}
+	for _, test := range tests {
+		if item := test.trekState.filteredItem(test.viewName, test.line); item != test.expected {
+			t.Errorf("filteredItem(%q, %d) with %+v = %d, expected %d",
+				test.viewName, test.line, test.trekState.filters["Jobs"], item, test.expected)
+		}
+	}
+}
+
+func TestFilteredLine(t *testing.T) {
+	tests := []struct {
+		trekState *trekStateType
+		viewName  string
+		item      int
+		expected  int
+	}{
+		{filterState("api", []int{4, 1, 7}), "Jobs", 4, 0},
+		{filterState("api", []int{4, 1, 7}), "Jobs", 1, 1},
+		{filterState("api", []int{4, 1, 7}), "Jobs", 7, 2},
+		// a hidden item falls back to the first line
+		{filterState("api", []int{4, 1, 7}), "Jobs", 3, 0},
+		{filterState("", []int{0, 1, 2}), "Jobs", 2, 2},
+		{filterState("api", []int{4, 1, 7}), "Tasks", 6, 6},
+		{&trekStateType{}, "Jobs", 3, 3},
+	}
+	for _, test := range tests {
+		if line := test.trekState.filteredLine(test.viewName, test.item); line != test.expected {
+			t.Errorf("filteredLine(%q, %d) with %+v = %d, expected %d",
+				test.viewName, test.item, test.trekState.filters["Jobs"], line, test.expected)
+		}
+	}
+}
+
+func TestFilteredItemAndLineRoundTrip(t *testing.T) {
+	trekState := filterState("api", []int{4, 1, 7})
+	for line := 0; line < 3; line++ {
+		if back := trekState.filteredLine("Jobs", trekState.filteredItem("Jobs", line)); back != line {
+			t.Errorf("line %d maps back to line %d", line, back)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 )
 
 // UIMode describes how the app should run
@@ -21,28 +22,105 @@ const (
 
 	// ListJobsMode is used to list jobs
 	ListJobsMode UIMode = "list-jobs"
+
+	// ListNodesMode is used to list client nodes
+	ListNodesMode UIMode = "list-nodes"
+
+	// NodeMode is used to describe a client node
+	NodeMode UIMode = "node"
+
+	// PlanMode is used to plan a local job file against the cluster
+	PlanMode UIMode = "plan"
+)
+
+// OutputFormat describes how one off commands print their results
+type OutputFormat string
+
+const (
+	// TextOutput renders results with Go templates (see display-format)
+	TextOutput OutputFormat = "text"
+
+	// JSONOutput renders results as JSON
+	JSONOutput OutputFormat = "json"
+
+	// YAMLOutput renders results as YAML
+	YAMLOutput OutputFormat = "yaml"
 )
 
 type trekOptions struct {
 	nomadAddress    string
+	nomadToken      string
+	nomadNamespace  string
+	nomadRegion     string
+	caCert          string
+	clientCert      string
+	clientKey       string
+	tlsServerName   string
+	tlsSkipVerify   bool
 	trekMode        UIMode
 	jobID           string
+	nodeName        string
 	taskGroup       string
 	allocationIndex int
+	allocationID    string
 	taskName        string
+	showLogs        bool
+	stderr          bool
+	follow          bool
+	tail            int
+	execCommand     string
+	fsList          string
+	fsCat           string
+	showStats       bool
+	statsInterval   time.Duration
+	readOnly        bool
+	spec            string
+	evaluations     bool
+	planFile        string
+	theme           string
 	displayFormat   string
+	outputFormat    OutputFormat
+	allocStatuses   allocationStatusFilter
 }
 
 type cliOptions struct {
 	nomadAddress    string
+	nomadToken      string
+	nomadNamespace  string
+	nomadRegion     string
+	caCert          string
+	clientCert      string
+	clientKey       string
+	tlsServerName   string
+	tlsSkipVerify   bool
 	help            bool
 	ncurses         bool
 	listJobs        bool
+	listNodes       bool
+	node            string
 	job             string
 	taskGroup       string
 	allocationIndex int
+	allocationID    string
 	taskName        string
+	showLogs        bool
+	stderr          bool
+	follow          bool
+	tail            int
+	execCommand     string
+	fsList          string
+	fsCat           string
+	showStats       bool
+	statsInterval   time.Duration
+	readOnly        bool
+	spec            string
+	evaluations     bool
+	plan            string
+	theme           string
 	displayFormat   string
+	outputFormat    string
+	allocStatus     string
+	allAllocations  bool
 }
 
 func (options *cliOptions) DetermineMode() UIMode {
@@ -55,7 +133,13 @@ func (options *cliOptions) DetermineMode() UIMode {
 			actualMode = NcursesMode
 		} else if options.listJobs {
 			actualMode = ListJobsMode
-		} else if options.job != "" {
+		} else if options.listNodes {
+			actualMode = ListNodesMode
+		} else if options.node != "" {
+			actualMode = NodeMode
+		} else if options.plan != "" {
+			actualMode = PlanMode
+		} else if options.job != "" || options.allocationID != "" {
 			actualMode = JobMode
 		}
 	}
@@ -67,24 +151,139 @@ func parseFlags() trekOptions {
 	options := new(cliOptions)
 	flag.BoolVar(&(*options).help, "help", false, "show usage prompt")
 	flag.StringVar(&(*options).nomadAddress, "nomad-address", "http://localhost:4646", "nomad cluster address")
+	flag.StringVar(&(*options).nomadToken, "token", "", "nomad ACL token (defaults to $NOMAD_TOKEN), not sent to the .trek.rc environments unless they set UseCommandLineToken")
+	flag.StringVar(&(*options).nomadNamespace, "namespace", "", "nomad namespace (defaults to $NOMAD_NAMESPACE)")
+	flag.StringVar(&(*options).nomadRegion, "region", "", "nomad region (defaults to $NOMAD_REGION)")
+	flag.StringVar(&(*options).caCert, "ca-cert", "", "path to a PEM-encoded CA cert used to verify the nomad server (defaults to $NOMAD_CACERT)")
+	flag.StringVar(&(*options).clientCert, "client-cert", "", "path to a PEM-encoded client certificate (defaults to $NOMAD_CLIENT_CERT)")
+	flag.StringVar(&(*options).clientKey, "client-key", "", "path to a PEM-encoded client key (defaults to $NOMAD_CLIENT_KEY)")
+	flag.StringVar(&(*options).tlsServerName, "tls-server-name", "", "server name used as SNI host when connecting via TLS (defaults to $NOMAD_TLS_SERVER_NAME)")
+	flag.BoolVar(&(*options).tlsSkipVerify, "tls-skip-verify", false, "do not verify the nomad server TLS certificate (defaults to $NOMAD_SKIP_VERIFY)")
 	flag.BoolVar(&(*options).ncurses, "ui", false, "use UI mode")
 	flag.BoolVar(&(*options).listJobs, "list-jobs", false, "list jobs")
+	flag.BoolVar(&(*options).listNodes, "list-nodes", false, "list client nodes")
+	flag.StringVar(&(*options).node, "node", "", "name or ID of the client node to describe (only used when running in non-ui mode)")
 	flag.StringVar(&(*options).job, "job", "", "job name to get (only used when running in non-ui mode)")
 	flag.StringVar(&(*options).taskGroup, "task-group", "", "task group to get (only used when running in non-ui mode)")
 	flag.IntVar(&(*options).allocationIndex, "allocation", -1, "allocation index to get (starts at 0, only used when running in non-ui mode)")
+	flag.StringVar(&(*options).allocationID, "alloc-id", "", "full or short allocation ID to get, job and task group are optional (only used when running in non-ui mode)")
 	flag.StringVar(&(*options).taskName, "task", "", "task name to get (only used when running in non-ui mode)")
+	flag.StringVar(&(*options).allocStatus, "alloc-status", "running", "comma-separated allocation statuses to show: pending, running, complete, failed, lost")
+	flag.BoolVar(&(*options).allAllocations, "all", false, "show allocations whatever their status (overrides alloc-status)")
+	flag.BoolVar(&(*options).showLogs, "logs", false, "show the logs of the selected task (only used when running in non-ui mode)")
+	flag.BoolVar(&(*options).stderr, "stderr", false, "show stderr instead of stdout (only used with -logs)")
+	flag.BoolVar(&(*options).follow, "follow", false, "keep streaming logs as they are written (only used with -logs)")
+	flag.IntVar(&(*options).tail, "tail", 0, "only show the last N lines of logs, 0 shows everything (only used with -logs)")
+	flag.StringVar(&(*options).execCommand, "exec", "", "command to run inside the selected task, attaching the terminal to it (only used when running in non-ui mode)")
+	flag.StringVar(&(*options).fsList, "fs-ls", "", "list a directory of the selected allocation, relative to the task directory when a task is given (only used when running in non-ui mode)")
+	flag.StringVar(&(*options).fsCat, "fs-cat", "", "print a file of the selected allocation, relative to the task directory when a task is given (only used when running in non-ui mode)")
+	flag.BoolVar(&(*options).showStats, "stats", false, "show the resource usage of the selected allocation, or of the selected task when one is given (only used when running in non-ui mode)")
+	flag.DurationVar(&(*options).statsInterval, "stats-interval", 0, "keep sampling resource usage at this interval, 0 prints a single sample (only used with -stats)")
+	flag.BoolVar(&(*options).readOnly, "read-only", false, "disable every action changing the cluster (exec in non-ui mode; exec, GC, job, allocation and deployment actions in ui mode)")
+	flag.StringVar(&(*options).spec, "spec", "", "print the specification of the selected job: json, or an approximate rendering as hcl (only used with -job)")
+	flag.BoolVar(&(*options).evaluations, "evals", false, "show the recent evaluations of the selected job, explaining why allocations could not be placed (only used with -job)")
+	flag.StringVar(&(*options).plan, "plan", "", "job file (HCL or JSON) to plan against the cluster, printing what submitting it would change")
+	flag.StringVar(&(*options).theme, "theme", "", "colors of the UI: color, monochrome or high-contrast (overrides .trek.rc; monochrome when $NO_COLOR is set)")
 	flag.StringVar(&(*options).displayFormat, "display-format", "", "task display format")
+	flag.StringVar(&(*options).outputFormat, "output", string(TextOutput), "output format: text, json or yaml (only used when running in non-ui mode)")
 
 	flag.Parse()
 
 	return trekOptions{
 		nomadAddress:    (*options).nomadAddress,
+		nomadToken:      (*options).nomadToken,
+		nomadNamespace:  (*options).nomadNamespace,
+		nomadRegion:     (*options).nomadRegion,
+		caCert:          (*options).caCert,
+		clientCert:      (*options).clientCert,
+		clientKey:       (*options).clientKey,
+		tlsServerName:   (*options).tlsServerName,
+		tlsSkipVerify:   (*options).tlsSkipVerify,
 		trekMode:        (*options).DetermineMode(),
 		jobID:           (*options).job,
+		nodeName:        (*options).node,
 		taskGroup:       (*options).taskGroup,
 		allocationIndex: (*options).allocationIndex,
+		allocationID:    (*options).allocationID,
 		taskName:        (*options).taskName,
+		showLogs:        (*options).showLogs,
+		stderr:          (*options).stderr,
+		follow:          (*options).follow,
+		tail:            (*options).tail,
+		execCommand:     (*options).execCommand,
+		fsList:          (*options).fsList,
+		fsCat:           (*options).fsCat,
+		showStats:       (*options).showStats,
+		statsInterval:   (*options).statsInterval,
+		readOnly:        (*options).readOnly,
+		spec:            (*options).spec,
+		evaluations:     (*options).evaluations,
+		planFile:        (*options).plan,
+		theme:           (*options).theme,
 		displayFormat:   (*options).displayFormat,
+		outputFormat:    OutputFormat((*options).outputFormat),
+		allocStatuses:   (*options).allocationStatuses(),
+	}
+}
+
+// logType returns the log stream selected by the user
+func (options trekOptions) logType() string {
+	if options.stderr {
+		return "stderr"
+	}
+	return "stdout"
+}
+
+func (options *cliOptions) allocationStatuses() allocationStatusFilter {
+	if options.allAllocations {
+		return nil
+	}
+	return parseAllocationStatusFilter(options.allocStatus)
+}
+
+// Validate checks the options that can't be validated by the flag package
+func (options trekOptions) Validate() error {
+	switch options.outputFormat {
+	case TextOutput, JSONOutput, YAMLOutput:
+	default:
+		return newTrekError(GenericError, nil, "unknown output format: %s", options.outputFormat)
+	}
+
+	switch options.spec {
+	case "", jsonSpec, hclSpec:
+	default:
+		return newTrekError(GenericError, nil, "unknown spec format: %s", options.spec)
+	}
+
+	if options.fsList != "" && options.fsCat != "" {
+		return newTrekError(GenericError, nil, "-fs-ls and -fs-cat cannot be used together")
+	}
+
+	return options.allocStatuses.Validate()
+}
+
+// browsesFiles tells whether the user asked for a file or directory of the
+// selected allocation
+func (options trekOptions) browsesFiles() bool {
+	return options.fsList != "" || options.fsCat != ""
+}
+
+// defaultEnvironment describes the environment configured from the command line
+func (options trekOptions) defaultEnvironment() environment {
+	return environment{
+		Name:      "default",
+		Address:   options.nomadAddress,
+		Token:     options.nomadToken,
+		Namespace: options.nomadNamespace,
+		Region:    options.nomadRegion,
+
+		CACert:        options.caCert,
+		ClientCert:    options.clientCert,
+		ClientKey:     options.clientKey,
+		TLSServerName: options.tlsServerName,
+		TLSSkipVerify: options.tlsSkipVerify,
+
+		commandLine: true,
 	}
 }
 
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	// filePreviewBytes bounds how much of a file the files panel shows
+	filePreviewBytes = 64 * 1024
+
+	filesViewName = "Files"
+)
+
+// allocationPath resolves a path relative to the directory of a task, or to
+// the allocation directory when no task is given
+func allocationPath(task string, filePath string) string {
+	return path.Join("/", task, filePath)
+}
+
+// listFiles lists a directory of an allocation, directories first
+func listFiles(client *nomad.Client, alloc *nomad.Allocation, dir string) ([]*nomad.AllocFileInfo, error) {
+	files, _, err := client.AllocFS().List(alloc, dir, &nomad.QueryOptions{})
+	if err != nil {
+		return nil, apiError(err, "cannot list %s", dir)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// catFile copies a file of an allocation into w
+func catFile(client *nomad.Client, alloc *nomad.Allocation, filePath string, w io.Writer) error {
+	info, _, err := client.AllocFS().Stat(alloc, filePath, &nomad.QueryOptions{})
+	if err != nil {
+		return apiError(err, "cannot stat %s", filePath)
+	}
+	if info.IsDir {
+		return newTrekError(GenericError, nil, "%s is a directory", filePath)
+	}
+
+	reader, err := client.AllocFS().Cat(alloc, filePath, &nomad.QueryOptions{})
+	if err != nil {
+		return apiError(err, "cannot read %s", filePath)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return apiError(err, "cannot read %s", filePath)
+	}
+	return nil
+}
+
+// readFilePreview reads the beginning of a file of an allocation
+func readFilePreview(client *nomad.Client, alloc *nomad.Allocation, file *nomad.AllocFileInfo, filePath string) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	if file.Size > filePreviewBytes {
+		reader, err = client.AllocFS().ReadAt(alloc, filePath, 0, filePreviewBytes, &nomad.QueryOptions{})
+	} else {
+		reader, err = client.AllocFS().Cat(alloc, filePath, &nomad.QueryOptions{})
+	}
+	if err != nil {
+		return nil, apiError(err, "cannot read %s", filePath)
+	}
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(reader, filePreviewBytes))
+	if err != nil {
+		return nil, apiError(err, "cannot read %s", filePath)
+	}
+	return content, nil
+}
+
+// filesViewState describes the files panel, which either lists a directory
+// or previews a file
+type filesViewState struct {
+	alloc      nomad.Allocation
+	dir        string
+	files      []*nomad.AllocFileInfo
+	preview    string
+	returnView string
+}
+
+func (files *filesViewState) title() string {
+	if files.preview != "" {
+		return fmt.Sprintf("Files: %s %s", shortID(files.alloc.ID), files.preview)
+	}
+	return fmt.Sprintf("Files: %s %s", shortID(files.alloc.ID), files.dir)
+}
+
+// browseFiles opens the files panel on the selected allocation, in the
+// directory of the selected task when inTask is set
+func browseFiles(inTask bool) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		alloc, err := trekState.CurrentAllocation()
+		if err != nil {
+			return openPopup(g, v, trekState, err.Error())
+		}
+
+		dir := allocationPath("", "")
+		if inTask {
+			task, err := trekState.CurrentTask()
+			if err != nil {
+				return openPopup(g, v, trekState, err.Error())
+			}
+			dir = allocationPath(task.Name, "")
+		}
+		files, err := listFiles(trekState.client, &alloc.allocation, dir)
+		if err != nil {
+			return openPopup(g, v, trekState, err.Error())
+		}
+
+		maxX, maxY := g.Size()
+		bounds := getBounds(maxX, maxY, 0, 1, 5)
+		view, err := g.SetView(filesViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+		if err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+		view.Editable = false
+		view.Wrap = false
+		trekState.theme.highlight(view)
+
+		trekState.files = &filesViewState{
+			alloc:      alloc.allocation,
+			dir:        dir,
+			files:      files,
+			returnView: v.Name(),
+		}
+		renderFiles(view, trekState, "")
+
+		_, err = g.SetCurrentView(filesViewName)
+		return err
+	}
+}
+
+// renderFiles lists the current directory, putting the cursor on the given
+// entry if it exists
+func renderFiles(v *gocui.View, trekState *trekStateType, selected string) {
+	files := trekState.files
+	v.Clear()
+	v.Highlight = true
+	v.Title = files.title()
+
+	selectedIndex := 0
+	for index, file := range files.files {
+		name := file.Name
+		if file.IsDir {
+			name += "/"
+		}
+		if file.Name == selected {
+			selectedIndex = index
+		}
+		fmt.Fprintf(v, "%-11s %10d %s %s\n", file.FileMode, file.Size, file.ModTime.Format("2006-01-02 15:04"), name)
+	}
+	if len(files.files) == 0 {
+		fmt.Fprintln(v, "(empty)")
+	}
+
+	v.SetOrigin(0, 0)
+	v.SetCursor(0, 0)
+	scrollTo(v, selectedIndex)
+}
+
+// openFileEntry enters the selected directory, or previews the selected file
+func openFileEntry(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	files := trekState.files
+	if files.preview != "" {
+		return nil
+	}
+
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	if oy+cy >= len(files.files) {
+		return nil
+	}
+	file := files.files[oy+cy]
+	filePath := path.Join(files.dir, file.Name)
+
+	if file.IsDir {
+		entries, err := listFiles(trekState.client, &files.alloc, filePath)
+		if err != nil {
+			return openPopup(g, v, trekState, err.Error())
+		}
+		files.dir = filePath
+		files.files = entries
+		renderFiles(v, trekState, "")
+		return nil
+	}
+
+	content, err := readFilePreview(trekState.client, &files.alloc, file, filePath)
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+
+	files.preview = filePath
+	v.Clear()
+	v.Highlight = false
+	v.Title = files.title()
+	v.SetOrigin(0, 0)
+	v.SetCursor(0, 0)
+	if bytes.IndexByte(content, 0) >= 0 {
+		fmt.Fprintln(v, "(binary file)")
+		return nil
+	}
+	v.Write(content)
+	if file.Size > int64(len(content)) {
+		fmt.Fprintf(v, "\n(showing the first %d of %d bytes)\n", len(content), file.Size)
+	}
+	return nil
+}
+
+// leaveFileEntry goes back from a preview to its directory, or from a
+// directory to its parent.  The panel is closed when leaving the root.
+func leaveFileEntry(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	files := trekState.files
+	if files.preview != "" {
+		selected := path.Base(files.preview)
+		files.preview = ""
+		renderFiles(v, trekState, selected)
+		return nil
+	}
+
+	if files.dir == "/" {
+		return closeFiles(g, v, trekState)
+	}
+
+	parent := path.Dir(files.dir)
+	entries, err := listFiles(trekState.client, &files.alloc, parent)
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+	selected := path.Base(files.dir)
+	files.dir = parent
+	files.files = entries
+	renderFiles(v, trekState, selected)
+	return nil
+}
+
+func closeFiles(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	returnView := trekState.files.returnView
+	trekState.files = nil
+	if err := g.DeleteView(filesViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView(returnView)
+	return err
+}
+
+// moveInFiles moves the cursor when listing a directory, and scrolls when
+// previewing a file
+func moveInFiles(lines int) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		if trekState.files.preview != "" {
+			return scrollView(v, lines)
+		}
+
+		_, oy := v.Origin()
+		_, cy := v.Cursor()
+		selected := oy + cy + lines
+		if selected > len(trekState.files.files)-1 {
+			selected = len(trekState.files.files) - 1
+		}
+		if selected < 0 {
+			selected = 0
+		}
+		scrollTo(v, selected)
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jroimartin/gocui"
+)
+
+const helpViewName = "Help"
+
+// helpViewState describes the help panel
+type helpViewState struct {
+	returnView string
+}
+
+// menuHiddenActions are left out of the menu bar: they move around a panel
+// and are the same everywhere
+var menuHiddenActions = map[string]bool{
+	"up":             true,
+	"down":           true,
+	"page-up":        true,
+	"page-down":      true,
+	"top":            true,
+	"bottom":         true,
+	"next-match":     true,
+	"previous-match": true,
+}
+
+// actionKeys groups the keys of a panel by action, in the order the actions
+// are bound
+type actionKeys struct {
+	action string
+	keys   []interface{}
+}
+
+func bindingsOf(resolved []binding, panel string) []actionKeys {
+	groups := make([]actionKeys, 0)
+	indexes := make(map[string]int)
+	for _, binding := range resolved {
+		if binding.panelName != panel {
+			continue
+		}
+		index, ok := indexes[binding.action]
+		if !ok {
+			index = len(groups)
+			indexes[binding.action] = index
+			groups = append(groups, actionKeys{action: binding.action})
+		}
+		groups[index].keys = append(groups[index].keys, binding.key)
+	}
+	return groups
+}
+
+// menuKey shows a key in the menu bar: named keys are upper-cased, while
+// characters keep their case
+func menuKey(key interface{}) string {
+	name := keyName(key)
+	if utf8.RuneCountInString(name) > 1 {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// menuItems lists the main actions of a panel followed by the global ones,
+// with the first key bound to each
+func menuItems(resolved []binding, panel string) []string {
+	items := make([]string, 0)
+	add := func(group actionKeys) {
+		items = append(items, fmt.Sprintf("%s:%s", menuKey(group.keys[0]), strings.ToUpper(group.action)))
+	}
+
+	global := bindingsOf(resolved, "")
+	for _, group := range global {
+		if group.action == "help" {
+			add(group)
+		}
+	}
+	if panel != "" {
+		for _, group := range bindingsOf(resolved, panel) {
+			if !menuHiddenActions[group.action] {
+				add(group)
+			}
+		}
+	}
+	for _, group := range global {
+		if group.action != "help" {
+			add(group)
+		}
+	}
+	return items
+}
+
+// showHelp opens a panel listing the keys of the focused panel, and the
+// global keys.  Pressing help again closes it.
+func showHelp(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	if v == nil {
+		return nil
+	}
+	if v.Name() == helpViewName {
+		return closeHelp(g, v, trekState)
+	}
+
+	maxX, maxY := g.Size()
+	bounds := getBounds(maxX, maxY, 0, 1, 5)
+	view, err := g.SetView(helpViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Editable = false
+	view.Wrap = false
+	view.Clear()
+	view.SetOrigin(0, 0)
+
+	trekState.help = &helpViewState{returnView: v.Name()}
+	view.Title = fmt.Sprintf("Help: %s", v.Name())
+	writeHelp(view, trekState.bindings, v.Name())
+
+	_, err = g.SetCurrentView(helpViewName)
+	return err
+}
+
+// writeHelp prints the actions of a panel and the global ones, with their keys
+func writeHelp(w io.Writer, resolved []binding, panel string) {
+	sections := []struct {
+		title  string
+		groups []actionKeys
+	}{
+		{panel, bindingsOf(resolved, panel)},
+		{"Global", bindingsOf(resolved, "")},
+	}
+
+	for index, section := range sections {
+		if index > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, section.title)
+		if len(section.groups) == 0 {
+			fmt.Fprintln(w, "  no keys")
+		}
+		for _, group := range section.groups {
+			names := make([]string, 0, len(group.keys))
+			for _, key := range group.keys {
+				names = append(names, keyName(key))
+			}
+			fmt.Fprintf(w, "  %-16s %s\n", group.action, strings.Join(names, ", "))
+		}
+	}
+}
+
+func closeHelp(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	returnView := trekState.help.returnView
+	trekState.help = nil
+	if err := g.DeleteView(helpViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView(returnView)
+	return err
+}
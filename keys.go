@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jroimartin/gocui"
+)
+
+// keymap rebinds the actions of the UI, as set in .trek.rc.  Each action is
+// given the list of keys triggering it, replacing its default keys.
+type keymap struct {
+	// Preset adds the keys of a built-in keymap to the defaults (only "vim"
+	// for now), before the actions below are applied
+	Preset string
+	// Global actions are available in every panel
+	Global map[string][]string
+	// Panels maps the name of a panel to its actions
+	Panels map[string]map[string][]string
+}
+
+type namedKey struct {
+	name string
+	key  gocui.Key
+}
+
+// namedKeys lists the special keys by name.  When several names share a key,
+// the first one is used to display it.
+var namedKeys = []namedKey{
+	{"enter", gocui.KeyEnter},
+	{"esc", gocui.KeyEsc},
+	{"tab", gocui.KeyTab},
+	{"space", gocui.KeySpace},
+	{"backspace", gocui.KeyBackspace2},
+	{"up", gocui.KeyArrowUp},
+	{"down", gocui.KeyArrowDown},
+	{"left", gocui.KeyArrowLeft},
+	{"right", gocui.KeyArrowRight},
+	{"pgup", gocui.KeyPgup},
+	{"pgdn", gocui.KeyPgdn},
+	{"home", gocui.KeyHome},
+	{"end", gocui.KeyEnd},
+	{"insert", gocui.KeyInsert},
+	{"delete", gocui.KeyDelete},
+	{"f1", gocui.KeyF1},
+	{"f2", gocui.KeyF2},
+	{"f3", gocui.KeyF3},
+	{"f4", gocui.KeyF4},
+	{"f5", gocui.KeyF5},
+	{"f6", gocui.KeyF6},
+	{"f7", gocui.KeyF7},
+	{"f8", gocui.KeyF8},
+	{"f9", gocui.KeyF9},
+	{"f10", gocui.KeyF10},
+	{"f11", gocui.KeyF11},
+	{"f12", gocui.KeyF12},
+}
+
+func init() {
+	for offset := 0; offset < 26; offset++ {
+		name := fmt.Sprintf("ctrl-%c", 'a'+offset)
+		namedKeys = append(namedKeys, namedKey{name, gocui.KeyCtrlA + gocui.Key(offset)})
+	}
+}
+
+// parseKey turns the name of a key into a gocui key: a single character, or
+// one of namedKeys (case-insensitive)
+func parseKey(name string) (interface{}, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		if r == ' ' {
+			return gocui.KeySpace, nil
+		}
+		return r, nil
+	}
+	for _, named := range namedKeys {
+		if strings.EqualFold(named.name, name) {
+			return named.key, nil
+		}
+	}
+	return nil, newTrekError(GenericError, nil, "unknown key %q", name)
+}
+
+// keyName describes a key the way parseKey reads it
+func keyName(key interface{}) string {
+	switch key := key.(type) {
+	case rune:
+		return string(key)
+	case gocui.Key:
+		for _, named := range namedKeys {
+			if named.key == key {
+				return named.name
+			}
+		}
+		return fmt.Sprintf("key %d", key)
+	default:
+		return fmt.Sprint(key)
+	}
+}
+
+// vimPreset binds the moves of vim to the actions of every panel having
+// them, leaving alone the keys a panel already uses
+var vimPreset = map[string][]string{
+	"up":        {"k"},
+	"down":      {"j"},
+	"back":      {"h"},
+	"select":    {"l"},
+	"parent":    {"h"},
+	"open":      {"l"},
+	"close":     {"h", "q"},
+	"top":       {"g"},
+	"bottom":    {"G"},
+	"page-up":   {"ctrl-b"},
+	"page-down": {"ctrl-f"},
+}
+
+// keyPresets lists the built-in keymaps
+var keyPresets = map[string]map[string][]string{
+	"vim": vimPreset,
+}
+
+// textInputPanels are panels the user types in: letters must reach them
+var textInputPanels = map[string]bool{
+	promptViewName: true,
+}
+
+// resolveBindings applies a keymap to the default bindings
+func resolveBindings(defaults []binding, keys *keymap) ([]binding, error) {
+	resolved := append([]binding(nil), defaults...)
+	if keys == nil {
+		return resolved, nil
+	}
+
+	if keys.Preset != "" {
+		preset, ok := keyPresets[keys.Preset]
+		if !ok {
+			return nil, newTrekError(GenericError, nil, "unknown key preset %q", keys.Preset)
+		}
+		resolved = applyPreset(resolved, preset)
+	}
+
+	var err error
+	if resolved, err = rebindActions(resolved, "", keys.Global); err != nil {
+		return nil, err
+	}
+
+	panels := make([]string, 0, len(keys.Panels))
+	for panel := range keys.Panels {
+		panels = append(panels, panel)
+	}
+	sort.Strings(panels)
+	for _, panel := range panels {
+		if panel == "" || len(panelActions(resolved, panel)) == 0 {
+			return nil, newTrekError(GenericError, nil, "unknown panel %q in keymap (expected one of %s)",
+				panel, strings.Join(panelNames(resolved), ", "))
+		}
+		if resolved, err = rebindActions(resolved, panel, keys.Panels[panel]); err != nil {
+			return nil, err
+		}
+	}
+
+	return resolved, validateBindings(resolved)
+}
+
+// applyPreset adds the keys of a preset to the actions having them
+func applyPreset(resolved []binding, preset map[string][]string) []binding {
+	used := make(map[string]map[interface{}]bool)
+	for _, binding := range resolved {
+		if used[binding.panelName] == nil {
+			used[binding.panelName] = make(map[interface{}]bool)
+		}
+		used[binding.panelName][binding.key] = true
+	}
+
+	added := make([]binding, 0)
+	done := make(map[string]bool)
+	for _, existing := range resolved {
+		names, ok := preset[existing.action]
+		id := existing.panelName + "\x00" + existing.action
+		if !ok || done[id] || textInputPanels[existing.panelName] {
+			continue
+		}
+		done[id] = true
+
+		for _, name := range names {
+			key, err := parseKey(name)
+			if err != nil || used[existing.panelName][key] || used[""][key] {
+				continue
+			}
+			used[existing.panelName][key] = true
+			added = append(added, binding{panelName: existing.panelName, key: key, action: existing.action, handler: existing.handler})
+		}
+	}
+	return append(resolved, added...)
+}
+
+// rebindActions replaces the keys of the given actions of a panel
+func rebindActions(resolved []binding, panel string, actions map[string][]string) ([]binding, error) {
+	scope := describePanel(panel)
+
+	names := make([]string, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+
+	for _, action := range names {
+		var handler uiHandlerWithStateType
+		kept := make([]binding, 0, len(resolved))
+		for _, existing := range resolved {
+			if existing.panelName == panel && existing.action == action {
+				handler = existing.handler
+				continue
+			}
+			kept = append(kept, existing)
+		}
+		if handler == nil {
+			return nil, newTrekError(GenericError, nil, "unknown action %q in %s (expected one of %s)",
+				action, scope, strings.Join(panelActions(resolved, panel), ", "))
+		}
+
+		bound := make(map[interface{}]bool)
+		for _, name := range actions[action] {
+			key, err := parseKey(name)
+			if err != nil {
+				return nil, newTrekError(GenericError, err, "invalid key for action %s in %s", action, scope)
+			}
+			if !bound[key] {
+				bound[key] = true
+				kept = append(kept, binding{panelName: panel, key: key, action: action, handler: handler})
+			}
+		}
+		resolved = kept
+	}
+	return resolved, nil
+}
+
+// validateBindings makes sure a key triggers a single action in a panel, and
+// that letters still reach the panels the user types in
+func validateBindings(resolved []binding) error {
+	actions := make(map[string]map[interface{}]string)
+	for _, binding := range resolved {
+		if actions[binding.panelName] == nil {
+			actions[binding.panelName] = make(map[interface{}]string)
+		}
+		if previous, ok := actions[binding.panelName][binding.key]; ok && previous != binding.action {
+			return newTrekError(GenericError, nil, "key %s is bound to both %s and %s in %s",
+				keyName(binding.key), previous, binding.action, describePanel(binding.panelName))
+		}
+		actions[binding.panelName][binding.key] = binding.action
+	}
+
+	for _, binding := range resolved {
+		if _, ok := binding.key.(rune); ok && (binding.panelName == "" || textInputPanels[binding.panelName]) {
+			return newTrekError(GenericError, nil, "key %s cannot be bound to %s in %s, it would break text input",
+				keyName(binding.key), binding.action, describePanel(binding.panelName))
+		}
+		if binding.panelName == "" {
+			continue
+		}
+		if global, ok := actions[""][binding.key]; ok {
+			return newTrekError(GenericError, nil, "key %s is bound to both %s (global) and %s in %s",
+				keyName(binding.key), global, binding.action, describePanel(binding.panelName))
+		}
+	}
+	return nil
+}
+
+func describePanel(panel string) string {
+	if panel == "" {
+		return "global keys"
+	}
+	return "panel " + panel
+}
+
+// panelActions lists the actions of a panel
+func panelActions(resolved []binding, panel string) []string {
+	seen := make(map[string]bool)
+	actions := make([]string, 0)
+	for _, binding := range resolved {
+		if binding.panelName == panel && !seen[binding.action] {
+			seen[binding.action] = true
+			actions = append(actions, binding.action)
+		}
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// panelNames lists the panels having bindings
+func panelNames(resolved []binding) []string {
+	seen := make(map[string]bool)
+	panels := make([]string, 0)
+	for _, binding := range resolved {
+		if binding.panelName != "" && !seen[binding.panelName] {
+			seen[binding.panelName] = true
+			panels = append(panels, binding.panelName)
+		}
+	}
+	sort.Strings(panels)
+	return panels
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  interface{}
+	}{
+		{"s", 's'},
+		{"S", 'S'},
+		{"/", '/'},
+		{" ", gocui.KeySpace},
+		{"space", gocui.KeySpace},
+		{"enter", gocui.KeyEnter},
+		{"Enter", gocui.KeyEnter},
+		{"esc", gocui.KeyEsc},
+		{"pgdn", gocui.KeyPgdn},
+		{"f1", gocui.KeyF1},
+		{"F12", gocui.KeyF12},
+		{"ctrl-a", gocui.KeyCtrlA},
+		{"ctrl-z", gocui.KeyCtrlZ},
+	}
+	for _, test := range tests {
+		key, err := parseKey(test.name)
+		if err != nil {
+			t.Errorf("parseKey(%q): unexpected error %v", test.name, err)
+			continue
+		}
+		if key != test.key {
+			t.Errorf("parseKey(%q) = %v, expected %v", test.name, key, test.key)
+		}
+	}
+
+	for _, name := range []string{"", "ctrl-", "f13", "shift-a", "enterr"} {
+		if _, err := parseKey(name); err == nil {
+			t.Errorf("parseKey(%q): expected an error", name)
+		}
+	}
+}
+
+func TestKeyNameRoundTrip(t *testing.T) {
+	for _, named := range namedKeys {
+		key, err := parseKey(keyName(named.key))
+		if err != nil || key != named.key {
+			t.Errorf("parseKey(keyName(%v)) = %v, %v", named.key, key, err)
+		}
+	}
+}
+
+// keysOf lists the keys of an action of a panel
+func keysOf(resolved []binding, panel string, action string) []interface{} {
+	keys := make([]interface{}, 0)
+	for _, binding := range resolved {
+		if binding.panelName == panel && binding.action == action {
+			keys = append(keys, binding.key)
+		}
+	}
+	return keys
+}
+
+func hasKey(keys []interface{}, key interface{}) bool {
+	for _, existing := range keys {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDefaultBindingsAreValid(t *testing.T) {
+	if err := validateBindings(bindings); err != nil {
+		t.Fatalf("default bindings: %v", err)
+	}
+	resolved, err := resolveBindings(bindings, nil)
+	if err != nil {
+		t.Fatalf("resolveBindings without keymap: %v", err)
+	}
+	if len(resolved) != len(bindings) {
+		t.Errorf("resolveBindings without keymap changed the bindings")
+	}
+}
+
+func TestResolveBindingsRebindsActions(t *testing.T) {
+	resolved, err := resolveBindings(bindings, &keymap{
+		Global: map[string][]string{"quit": {"ctrl-q", "f12", "f12"}},
+		Panels: map[string]map[string][]string{"Jobs": {"stop": {"s"}}},
+	})
+	if err != nil {
+		t.Fatalf("resolveBindings: %v", err)
+	}
+
+	quit := keysOf(resolved, "", "quit")
+	if len(quit) != 2 || !hasKey(quit, gocui.KeyCtrlQ) || !hasKey(quit, gocui.KeyF12) {
+		t.Errorf("global quit keys = %v, expected ctrl-q and f12", quit)
+	}
+	stop := keysOf(resolved, "Jobs", "stop")
+	if len(stop) != 1 || stop[0] != 's' {
+		t.Errorf("Jobs stop keys = %v, expected s", stop)
+	}
+	if purge := keysOf(resolved, "Jobs", "purge"); !hasKey(purge, 'X') {
+		t.Errorf("Jobs purge keys = %v, expected the default X", purge)
+	}
+}
+
+func TestResolveBindingsVimPreset(t *testing.T) {
+	resolved, err := resolveBindings(bindings, &keymap{Preset: "vim"})
+	if err != nil {
+		t.Fatalf("resolveBindings: %v", err)
+	}
+
+	if down := keysOf(resolved, "Jobs", "down"); !hasKey(down, 'j') || !hasKey(down, gocui.KeyArrowDown) {
+		t.Errorf("Jobs down keys = %v, expected j along with the arrow", down)
+	}
+	if bottom := keysOf(resolved, "Jobs", "bottom"); !hasKey(bottom, 'G') {
+		t.Errorf("Jobs bottom keys = %v, expected G", bottom)
+	}
+	// h already toggles the format of the spec panel
+	if closeKeys := keysOf(resolved, specViewName, "close"); hasKey(closeKeys, 'h') {
+		t.Errorf("Spec close keys = %v, h should be left to format", closeKeys)
+	}
+	// letters must reach the prompt
+	for _, binding := range resolved {
+		if _, ok := binding.key.(rune); ok && binding.panelName == promptViewName {
+			t.Errorf("the preset bound %s in the prompt", keyName(binding.key))
+		}
+	}
+}
+
+func TestResolveBindingsErrors(t *testing.T) {
+	tests := []struct {
+		keys     keymap
+		expected string
+	}{
+		{keymap{Preset: "emacs"}, `unknown key preset "emacs"`},
+		{keymap{Panels: map[string]map[string][]string{"Jobz": {"stop": {"s"}}}}, `unknown panel "Jobz"`},
+		{keymap{Panels: map[string]map[string][]string{"Jobs": {"stahp": {"s"}}}}, `unknown action "stahp" in panel Jobs`},
+		{keymap{Panels: map[string]map[string][]string{"Jobs": {"stop": {"shift-s"}}}}, `invalid key for action stop in panel Jobs`},
+		{keymap{Panels: map[string]map[string][]string{"Jobs": {"stop": {"/"}}}}, `key / is bound to both`},
+		{keymap{Global: map[string][]string{"quit": {"q"}}}, `key q cannot be bound to quit in global keys`},
+		{keymap{Global: map[string][]string{"refresh": {"enter"}}}, `key enter is bound to both refresh (global)`},
+	}
+	for _, test := range tests {
+		keys := test.keys
+		_, err := resolveBindings(bindings, &keys)
+		if err == nil {
+			t.Errorf("%+v: expected an error", test.keys)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%+v: error %q doesn't mention %q", test.keys, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	// logBytesPerLine estimates the length of a log line, and is used to
+	// compute where to start reading logs when tailing them
+	logBytesPerLine = 120
+
+	// logWindowGrowth is how much the window read at the end of the logs
+	// grows when it holds fewer lines than tailed
+	logWindowGrowth = 4
+
+	// logUnblockTime is how long we wait for the initial chunk of logs
+	// before considering it complete when following them
+	logUnblockTime = 500 * time.Millisecond
+
+	// logViewTail is how many lines the log panel shows initially
+	logViewTail = 500
+
+	logsViewName = "Logs"
+)
+
+// logSource opens a stream of log frames starting offset bytes away from
+// origin.  Closing cancel ends the stream.
+type logSource func(origin string, offset int64, follow bool, cancel chan struct{}) (<-chan *nomad.StreamFrame, <-chan error)
+
+// streamLogs copies the stdout or stderr logs of a task into w.  When tail is
+// positive, only the last tail lines are copied before following the logs (if
+// follow is set).  Closing stop interrupts the stream.
+func streamLogs(client *nomad.Client, alloc *nomad.Allocation, task string, logType string, follow bool, tail int, w io.Writer, stop <-chan struct{}) error {
+	source := func(origin string, offset int64, follow bool, cancel chan struct{}) (<-chan *nomad.StreamFrame, <-chan error) {
+		return client.AllocFS().Logs(alloc, follow, task, logType, origin, offset, cancel, &nomad.QueryOptions{})
+	}
+	if err := copyLogs(source, follow, tail, w, stop); err != nil {
+		return apiError(err, "cannot read %s of task %s", logType, task)
+	}
+	return nil
+}
+
+// copyLogs copies the logs read from source into w, like streamLogs.  The last
+// tail lines are found by reading windows of the end of the logs, each larger
+// than the previous one, until one holds enough lines or the whole logs.
+func copyLogs(source logSource, follow bool, tail int, w io.Writer, stop <-chan struct{}) error {
+	if tail <= 0 {
+		reader, closeReader := openLogs(source, nomad.OriginStart, 0, follow, stop)
+		defer closeReader()
+		_, err := io.Copy(w, reader)
+		return err
+	}
+
+	window := int64(tail * logBytesPerLine)
+	for {
+		reader, closeReader := openLogs(source, nomad.OriginEnd, window, follow, stop)
+		text, err := readInitialLogs(reader, follow)
+		if err != nil {
+			closeReader()
+			return err
+		}
+
+		// Unless it holds the whole logs, the window starts in the middle of
+		// a line, which doesn't count
+		if int64(len(text)) < window || strings.Count(text, "\n") > tail {
+			defer closeReader()
+			if _, err := io.WriteString(w, lastLines(text, tail)); err != nil {
+				return err
+			}
+			if !follow {
+				return nil
+			}
+			reader.SetUnblockTime(0)
+			_, err := io.Copy(w, reader)
+			return err
+		}
+		closeReader()
+		window *= logWindowGrowth
+	}
+}
+
+// openLogs starts reading the logs of source.  The reader is closed by the
+// returned function, or when stop is closed.
+func openLogs(source logSource, origin string, offset int64, follow bool, stop <-chan struct{}) (*nomad.FrameReader, func()) {
+	cancel := make(chan struct{})
+	frames, errCh := source(origin, offset, follow, cancel)
+	reader := nomad.NewFrameReader(frames, errCh, cancel)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+			reader.Close()
+		case <-done:
+		}
+	}()
+	return reader, func() {
+		close(done)
+		reader.Close()
+	}
+}
+
+// readInitialLogs reads the logs available right away: all of them, or when
+// following them, those received before the stream pauses
+func readInitialLogs(reader *nomad.FrameReader, follow bool) (string, error) {
+	if follow {
+		reader.SetUnblockTime(logUnblockTime)
+	}
+
+	var buffer bytes.Buffer
+	chunk := make([]byte, 4096)
+	for {
+		n, err := reader.Read(chunk)
+		buffer.Write(chunk[:n])
+		if err == io.EOF {
+			return buffer.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			// Nothing received for a while, the initial chunk is complete
+			return buffer.String(), nil
+		}
+	}
+}
+
+// lastLines returns the last count lines of a text
+func lastLines(text string, count int) string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "")
+}
+
+// logViewState describes the log panel
+type logViewState struct {
+	// task is the name of the task whose logs are streamed
+	task    string
+	logType string
+	follow  bool
+	search  string
+	stop    chan struct{}
+
+	pendingLock sync.Mutex
+	pending     [][]byte
+}
+
+// appendPending buffers log chunks until the UI goroutine appends them to the
+// panel, as gocui doesn't guarantee the order in which updates are run
+func (logs *logViewState) appendPending(p []byte) {
+	logs.pendingLock.Lock()
+	logs.pending = append(logs.pending, append([]byte(nil), p...))
+	logs.pendingLock.Unlock()
+}
+
+func (logs *logViewState) flush(v *gocui.View) {
+	logs.pendingLock.Lock()
+	pending := logs.pending
+	logs.pending = nil
+	logs.pendingLock.Unlock()
+
+	for _, chunk := range pending {
+		v.Write(chunk)
+	}
+	if len(pending) > 0 && logs.follow {
+		scrollToBottom(v)
+	}
+}
+
+// guiWriter appends everything written to it to the log panel, until the
+// stream it belongs to is stopped
+type guiWriter struct {
+	g    *gocui.Gui
+	logs *logViewState
+	stop chan struct{}
+}
+
+func (w guiWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.stop:
+		return 0, io.EOF
+	default:
+	}
+
+	w.logs.appendPending(p)
+	w.g.Update(func(g *gocui.Gui) error {
+		if v, err := g.View(logsViewName); err == nil {
+			w.logs.flush(v)
+		}
+		return nil
+	})
+	return len(p), nil
+}
+
+func (logs *logViewState) title() string {
+	if logs.follow {
+		return fmt.Sprintf("Logs: %s (%s, follow)", logs.task, logs.logType)
+	}
+	return fmt.Sprintf("Logs: %s (%s)", logs.task, logs.logType)
+}
+
+func (trekState *trekStateType) stopLogs() {
+	if trekState.logs != nil && trekState.logs.stop != nil {
+		close(trekState.logs.stop)
+		trekState.logs.stop = nil
+	}
+}
+
+// startLogs streams the logs of the selected task into the log panel
+func startLogs(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	trekState.stopLogs()
+
+	alloc, err := trekState.CurrentAllocation()
+	if err != nil {
+		return err
+	}
+	currentTask, err := trekState.CurrentTask()
+	if err != nil {
+		return err
+	}
+	task := currentTask.Name
+	logs := trekState.logs
+	logs.task = task
+	logs.stop = make(chan struct{})
+	logs.pendingLock.Lock()
+	logs.pending = nil
+	logs.pendingLock.Unlock()
+
+	v.Clear()
+	v.SetOrigin(0, 0)
+	v.SetCursor(0, 0)
+	v.Title = logs.title()
+
+	client := trekState.client
+	logType := logs.logType
+	stop := logs.stop
+	writer := guiWriter{g: g, logs: logs, stop: stop}
+	go func() {
+		if err := streamLogs(client, &alloc.allocation, task, logType, true, logViewTail, writer, stop); err != nil {
+			fmt.Fprintf(writer, "\n%s\n", err)
+		}
+	}()
+	return nil
+}
+
+func showLogs(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	maxX, maxY := g.Size()
+	bounds := getBounds(maxX, maxY, 0, 1, 5)
+	view, err := g.SetView(logsViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Wrap = false
+	view.Editable = false
+	view.Autoscroll = false
+	trekState.theme.highlight(view)
+
+	trekState.logs = &logViewState{logType: "stdout", follow: true}
+	if err := startLogs(g, view, trekState); err != nil {
+		g.DeleteView(logsViewName)
+		return openPopup(g, v, trekState, err.Error())
+	}
+	_, err = g.SetCurrentView(logsViewName)
+	return err
+}
+
+func closeLogs(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	trekState.stopLogs()
+	trekState.logs = nil
+	if err := g.DeleteView(logsViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView("Task")
+	return err
+}
+
+func toggleLogStream(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	if trekState.logs.logType == "stdout" {
+		trekState.logs.logType = "stderr"
+	} else {
+		trekState.logs.logType = "stdout"
+	}
+	return startLogs(g, v, trekState)
+}
+
+func toggleLogFollow(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	setLogFollow(v, trekState, !trekState.logs.follow)
+	return nil
+}
+
+// setLogFollow toggles follow mode, which keeps the panel scrolled to the
+// most recent logs
+func setLogFollow(v *gocui.View, trekState *trekStateType, follow bool) {
+	trekState.logs.follow = follow
+	v.Title = trekState.logs.title()
+	if follow {
+		v.Highlight = false
+		scrollToBottom(v)
+	}
+}
+
+func searchLogs(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	return openPrompt(g, v, trekState, "Search logs", func(g *gocui.Gui, trekState *trekStateType, value string) error {
+		trekState.logs.search = value
+		v, err := g.View(logsViewName)
+		if err != nil {
+			return nil
+		}
+		return findInLogs(v, trekState, 1, true)
+	})
+}
+
+func nextLogMatch(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	return findInLogs(v, trekState, 1, false)
+}
+
+func previousLogMatch(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	return findInLogs(v, trekState, -1, false)
+}
+
+// findInLogs moves the cursor to the next line (in the given direction)
+// containing the search term, stopping follow mode so the match stays visible
+func findInLogs(v *gocui.View, trekState *trekStateType, direction int, includeCurrent bool) error {
+	search := trekState.logs.search
+	if search == "" {
+		return nil
+	}
+
+	lines := v.BufferLines()
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	current := oy + cy
+
+	start := current + direction
+	if includeCurrent {
+		start = current
+	}
+	for step := 0; step < len(lines); step++ {
+		index := ((start+direction*step)%len(lines) + len(lines)) % len(lines)
+		if strings.Contains(lines[index], search) {
+			setLogFollow(v, trekState, false)
+			v.Highlight = true
+			scrollTo(v, index)
+			return nil
+		}
+	}
+	return nil
+}
+
+// scrollTo moves the cursor to a line of the buffer, scrolling if needed
+func scrollTo(v *gocui.View, line int) {
+	_, height := v.Size()
+	ox, oy := v.Origin()
+	if line < oy || line >= oy+height {
+		oy = line - height/2
+		if oy < 0 {
+			oy = 0
+		}
+	}
+	v.SetOrigin(ox, oy)
+	v.SetCursor(0, line-oy)
+}
+
+func scrollToBottom(v *gocui.View) {
+	_, height := v.Size()
+	lines := len(v.BufferLines())
+	oy := lines - height
+	if oy < 0 {
+		oy = 0
+	}
+	v.SetOrigin(0, oy)
+}
+
+// scrollLogs scrolls the log panel by the given number of lines, which stops
+// follow mode
+func scrollLogs(lines int) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		setLogFollow(v, trekState, false)
+		return scrollView(v, lines)
+	}
+}
+
+// scrollView scrolls a view by the given number of lines, without going past
+// its content
+func scrollView(v *gocui.View, lines int) error {
+	_, height := v.Size()
+	ox, oy := v.Origin()
+	oy += lines
+	if max := len(v.BufferLines()) - height; oy > max {
+		oy = max
+	}
+	if oy < 0 {
+		oy = 0
+	}
+	return v.SetOrigin(ox, oy)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// frameSource serves logs as the nomad API does, in frames of a few bytes,
+// and records the offsets it is asked for
+func frameSource(logs string, offsets *[]int64) logSource {
+	return func(origin string, offset int64, follow bool, cancel chan struct{}) (<-chan *nomad.StreamFrame, <-chan error) {
+		*offsets = append(*offsets, offset)
+		start := int64(0)
+		if origin == nomad.OriginEnd && offset < int64(len(logs)) {
+			start = int64(len(logs)) - offset
+		}
+
+		frames := make(chan *nomad.StreamFrame)
+		go func() {
+			defer close(frames)
+			for data := logs[start:]; len(data) > 0; {
+				size := 100
+				if len(data) < size {
+					size = len(data)
+				}
+				select {
+				case frames <- &nomad.StreamFrame{Offset: start, Data: []byte(data[:size])}:
+				case <-cancel:
+					return
+				}
+				start += int64(size)
+				data = data[size:]
+			}
+		}()
+		return frames, make(chan error)
+	}
+}
+
+// logLines builds count lines of the given length, newline included
+func logLines(first, count, length int) string {
+	var lines strings.Builder
+	for i := first; i < first+count; i++ {
+		line := fmt.Sprintf("line %d ", i)
+		lines.WriteString(line + strings.Repeat("x", length-len(line)-1) + "\n")
+	}
+	return lines.String()
+}
+
+func TestCopyLogsTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		logs     string
+		tail     int
+		expected string
+		reads    int
+	}{
+		{"short lines", logLines(0, 50, 20), 10, logLines(40, 10, 20), 1},
+		{"long lines", logLines(0, 50, 1000), 10, logLines(40, 10, 1000), 3},
+		{"more lines than logged", logLines(0, 5, 1000), 10, logLines(0, 5, 1000), 3},
+		{"line as long as the window", logLines(0, 2, 1200), 1, logLines(1, 1, 1200), 3},
+		{"unterminated last line", logLines(0, 50, 300) + "partial", 3, logLines(48, 2, 300) + "partial", 2},
+		{"whole logs", logLines(0, 3, 1000), 0, logLines(0, 3, 1000), 1},
+		{"no logs", "", 10, "", 1},
+	}
+	for _, test := range tests {
+		var offsets []int64
+		var output bytes.Buffer
+		if err := copyLogs(frameSource(test.logs, &offsets), false, test.tail, &output, nil); err != nil {
+			t.Errorf("%s: copyLogs: %v", test.name, err)
+			continue
+		}
+		if output.String() != test.expected {
+			t.Errorf("%s: copied %d lines, expected %d:\n%s", test.name,
+				strings.Count(output.String(), "\n"), strings.Count(test.expected, "\n"), output.String())
+		}
+		if len(offsets) != test.reads {
+			t.Errorf("%s: read the logs at offsets %v, expected %d reads", test.name, offsets, test.reads)
+		}
+	}
+}
+
+func TestCopyLogsStop(t *testing.T) {
+	// a stream which never sends anything
+	source := func(origin string, offset int64, follow bool, cancel chan struct{}) (<-chan *nomad.StreamFrame, <-chan error) {
+		return make(chan *nomad.StreamFrame), make(chan error)
+	}
+	stop := make(chan struct{})
+	close(stop)
+	var output bytes.Buffer
+	if err := copyLogs(source, true, 0, &output, stop); err != nil {
+		t.Fatalf("copyLogs: %v", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("copied %q from an empty stream", output.String())
+	}
+}
@@ -1,18 +1,36 @@
 package main
 
-import "log"
+import (
+	"fmt"
+	"os"
+)
 
 func main() {
 	options := parseFlags()
 
+	if err := options.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "trek: %s\n", err)
+		os.Exit(exitCode(err))
+	}
+
 	switch options.trekMode {
 	case NcursesMode:
-		runUI(options)
-	case ListJobsMode, JobMode:
-		runCommand(options)
+		if err := runUI(options); err != nil {
+			fmt.Fprintf(os.Stderr, "trek: %s\n", err)
+			os.Exit(exitCode(err))
+		}
+	case ListJobsMode, JobMode, ListNodesMode, NodeMode, PlanMode:
+		if err := runCommand(options); err != nil {
+			if _, ok := err.(commandExitError); !ok {
+				fmt.Fprintf(os.Stderr, "trek: %s\n", err)
+			}
+			os.Exit(exitCode(err))
+		}
 	case HelpMode:
 		usage()
 	default:
-		log.Panicf("trek: unknown mode %+v\n", options.trekMode)
+		err := newTrekError(GenericError, nil, "unknown mode %+v", options.trekMode)
+		fmt.Fprintf(os.Stderr, "trek: %s\n", err)
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	nodesViewName           = "Nodes"
+	nodeViewName            = "Node"
+	nodeAllocationsViewName = "Node Allocations"
+)
+
+// Nodes lists the client nodes of the cluster, sorted by name
+func (trekState *trekStateType) Nodes() ([]*nomad.NodeListStub, error) {
+	nodes, _, err := trekState.client.Nodes().List(&nomad.QueryOptions{})
+	if err != nil {
+		return nil, apiError(err, "cannot list nodes")
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	trekState.nodes = nodes
+	return nodes, nil
+}
+
+func (trekState *trekStateType) CurrentNode() (*nomad.NodeListStub, error) {
+	index := trekState.selectedNode
+	if index < 0 || index > len(trekState.nodes)-1 {
+		return nil, newTrekError(NotFoundError, nil, "node not found")
+	}
+	return trekState.nodes[index], nil
+}
+
+// nodeDetails fetches a node along with the allocations placed on it
+func (trekState *trekStateType) nodeDetails(nodeID string) (nodeFormatProvider, error) {
+	nodes := trekState.client.Nodes()
+	node, _, err := nodes.Info(nodeID, &nomad.QueryOptions{})
+	if err != nil {
+		return nodeFormatProvider{}, apiError(err, "cannot fetch node %s", nodeID)
+	}
+	allocations, _, err := nodes.Allocations(nodeID, &nomad.QueryOptions{})
+	if err != nil {
+		return nodeFormatProvider{}, apiError(err, "cannot list allocations of node %s", node.Name)
+	}
+	return buildNodeDetails(node, allocations), nil
+}
+
+// selectNodeByName selects a node given its name, or its full or short ID
+func selectNodeByName(trekState *trekStateType, name string) error {
+	nodes, err := trekState.Nodes()
+	if err != nil {
+		return err
+	}
+
+	matches := make([]string, 0)
+	for index, node := range nodes {
+		if node.Name == name || strings.HasPrefix(node.ID, strings.ToLower(name)) {
+			trekState.selectedNode = index
+			matches = append(matches, fmt.Sprintf("%s %s", node.ID, node.Name))
+		}
+	}
+
+	if len(matches) == 0 {
+		names := make([]string, 0)
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		return candidatesError(NotFoundError, fmt.Sprintf("node %s not found.  Available nodes:", name), names)
+	}
+	if len(matches) > 1 {
+		return candidatesError(AmbiguousSelectionError, fmt.Sprintf("node %s is ambiguous.  Matching nodes:", name), matches)
+	}
+	return nil
+}
+
+func selectNodes(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	_, err := g.View(nodesViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(nodesViewName)
+	}
+
+	if err := trekState.Connect(); err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+
+	trekState.trackView(selectNodes)
+
+	client := trekState.client
+	trekState.startWatch(g, panelWatch{
+		viewName: nodesViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			_, meta, err := client.Nodes().List(options)
+			return meta, err
+		},
+		render:    renderNodes,
+		selection: setSelectedNode,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    nodesViewName,
+			foregroundAfterCreation: true,
+			panelNum:                1,
+			panelsTotal:             5,
+			margin:                  0,
+			handler:                 renderNodes,
+		},
+		trekState,
+	)
+}
+
+func renderNodes(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	nodes, err := trekState.Nodes()
+	if err != nil {
+		fmt.Fprintln(view, err)
+		return nil
+	}
+
+	lines := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		drain := ""
+		if node.Drain {
+			drain = ", draining"
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s, %s%s) %s/%s",
+			node.Name, trekState.theme.statusText(node.Status), node.SchedulingEligibility, drain, node.Datacenter, node.NodeClass))
+	}
+	renderList(view, trekState, nodesViewName, lines)
+
+	return nil
+}
+
+func selectNode(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	node, err := trekState.CurrentNode()
+	if err != nil {
+		return nil
+	}
+
+	_, err = g.View(nodeViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(nodeViewName)
+	}
+
+	trekState.trackView(selectNode)
+
+	client := trekState.client
+	nodeID := node.ID
+	trekState.startWatch(g, panelWatch{
+		viewName: nodeViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			_, meta, err := client.Nodes().Info(nodeID, options)
+			return meta, err
+		},
+		render: renderNode,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    nodeViewName,
+			foregroundAfterCreation: true,
+			panelNum:                0,
+			panelsTotal:             1,
+			margin:                  10,
+			handler:                 renderNode,
+		},
+		trekState,
+	)
+}
+
+func renderNode(view *gocui.View, trekState *trekStateType) error {
+	view.Editable = false
+	view.Wrap = false
+
+	node, err := trekState.CurrentNode()
+	if err == nil {
+		var provider nodeFormatProvider
+		provider, err = trekState.nodeDetails(node.ID)
+		if err == nil {
+			view.Title = fmt.Sprintf("Node: %s", provider.Name)
+			err = trekPrintDetails(view, nodeDetailsFormat, provider)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(view, err)
+	}
+	return nil
+}
+
+func setSelectedNode(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedNode = trekState.filteredItem(nodesViewName, position.y)
+}
+
+// NodeAllocations lists the allocations placed on the selected node, sorted by
+// name
+func (trekState *trekStateType) NodeAllocations() ([]*nomad.Allocation, error) {
+	node, err := trekState.CurrentNode()
+	if err != nil {
+		return nil, err
+	}
+	allocations, _, err := trekState.client.Nodes().Allocations(node.ID, &nomad.QueryOptions{})
+	if err != nil {
+		return nil, apiError(err, "cannot list allocations of node %s", node.Name)
+	}
+
+	sort.SliceStable(allocations, func(i, j int) bool {
+		if allocations[i].Name == allocations[j].Name {
+			return allocations[i].CreateIndex > allocations[j].CreateIndex
+		}
+		return allocations[i].Name < allocations[j].Name
+	})
+	trekState.nodeAllocations = allocations
+	return allocations, nil
+}
+
+func (trekState *trekStateType) CurrentNodeAllocation() (*nomad.Allocation, error) {
+	index := trekState.selectedNodeAllocation
+	if index < 0 || index > len(trekState.nodeAllocations)-1 {
+		return nil, newTrekError(NotFoundError, nil, "allocation not found")
+	}
+	return trekState.nodeAllocations[index], nil
+}
+
+// selectNodeAllocations opens a panel listing the allocations of the selected
+// node
+func selectNodeAllocations(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	node, err := trekState.CurrentNode()
+	if err != nil {
+		return nil
+	}
+
+	_, err = g.View(nodeAllocationsViewName)
+
+	// if the view exists
+	if err == nil {
+		g.DeleteView(nodeAllocationsViewName)
+	}
+
+	trekState.trackView(selectNodeAllocations)
+
+	client := trekState.client
+	nodeID := node.ID
+	trekState.startWatch(g, panelWatch{
+		viewName: nodeAllocationsViewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			_, meta, err := client.Nodes().Allocations(nodeID, options)
+			return meta, err
+		},
+		render:    renderNodeAllocations,
+		selection: setSelectedNodeAllocation,
+	})
+
+	return createView(g,
+		trekView{
+			name:                    nodeAllocationsViewName,
+			foregroundAfterCreation: true,
+			panelNum:                2,
+			panelsTotal:             5,
+			margin:                  0,
+			handler:                 renderNodeAllocations,
+		},
+		trekState,
+	)
+}
+
+func renderNodeAllocations(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	allocations, err := trekState.NodeAllocations()
+	if err != nil {
+		fmt.Fprintln(view, err)
+		return nil
+	}
+
+	lines := make([]string, 0, len(allocations))
+	for _, alloc := range allocations {
+		lines = append(lines, fmt.Sprintf("%s (%s) %s", alloc.Name, trekState.theme.statusText(alloc.ClientStatus), shortID(alloc.ID)))
+	}
+	renderList(view, trekState, nodeAllocationsViewName, lines)
+
+	return nil
+}
+
+func setSelectedNodeAllocation(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedNodeAllocation = trekState.filteredItem(nodeAllocationsViewName, position.y)
+}
+
+// openNodeAllocation leaves the nodes explorer for the panels of the job of
+// the selected allocation, down to its tasks, selecting the allocation along
+// the way so that every action of these panels applies to it
+func openNodeAllocation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	alloc, err := trekState.CurrentNodeAllocation()
+	if err != nil {
+		return nil
+	}
+
+	// Close the nodes explorer, back to the Clusters panel
+	for _, viewName := range []string{nodeAllocationsViewName, nodeViewName, nodesViewName} {
+		if _, err := g.View(viewName); err != nil {
+			continue
+		}
+		if err := deleteView(viewName, "Clusters", func(trekState *trekStateType) {})(g, v, trekState); err != nil {
+			return err
+		}
+	}
+	for _, viewName := range []string{"Jobs", "Task Groups", "Allocations", "Tasks"} {
+		delete(trekState.filters, viewName)
+	}
+	if !trekState.allocationStatusFilter.Matches(alloc.ClientStatus) {
+		trekState.allocationStatusFilter = nil
+	}
+
+	clusters, err := g.View("Clusters")
+	if err != nil {
+		return err
+	}
+	if err := selectCluster(g, clusters, trekState); err != nil {
+		return err
+	}
+
+	// each step lists the items of its panel, in which the allocation is found
+	steps := []struct {
+		viewName string
+		find     func() int
+		open     uiHandlerWithStateType
+	}{
+		{"Jobs", func() int {
+			trekState.selectedJob = -1
+			for index, job := range trekState.jobs {
+				if job.ID == alloc.JobID && job.Namespace == alloc.Namespace {
+					trekState.selectedJob = index
+				}
+			}
+			return trekState.selectedJob
+		}, selectJob},
+		{"Task Groups", func() int {
+			trekState.selectedAllocationGroup = -1
+			for index, taskGroup := range trekState.CurrentTaskGroups() {
+				if *taskGroup.Name == alloc.TaskGroup {
+					trekState.selectedAllocationGroup = index
+				}
+			}
+			return trekState.selectedAllocationGroup
+		}, selectTaskGroup},
+		{"Allocations", func() int {
+			trekState.selectedAllocationIndex = -1
+			for index, found := range trekState.foundAllocations {
+				if found.ID == alloc.ID {
+					trekState.selectedAllocationIndex = index
+				}
+			}
+			return trekState.selectedAllocationIndex
+		}, selectAllocation},
+	}
+	for _, step := range steps {
+		view, err := g.View(step.viewName)
+		if err != nil {
+			// the previous panel reported why it couldn't open this one
+			return nil
+		}
+		line := step.find()
+		if line < 0 {
+			return openPopup(g, view, trekState, fmt.Sprintf("allocation %s not found in the %s panel", shortID(alloc.ID), step.viewName))
+		}
+		scrollTo(view, trekState.filteredLine(step.viewName, line))
+		if err := step.open(g, view, trekState); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+	"golang.org/x/term"
+)
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+
+	planViewName = "Plan"
+)
+
+// parseJobFile reads a job file, either as JSON (bare or wrapped in a "Job"
+// key, like `nomad job inspect` prints it) or as HCL, parsed by the cluster
+func parseJobFile(client *nomad.Client, path string) (*nomad.Job, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newTrekError(NotFoundError, err, "cannot read job file %s", path)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if filepath.Ext(path) == ".json" || bytes.HasPrefix(trimmed, []byte("{")) {
+		var wrapped struct {
+			Job *nomad.Job
+		}
+		if err := json.Unmarshal(trimmed, &wrapped); err != nil {
+			return nil, newTrekError(GenericError, err, "cannot parse job file %s", path)
+		}
+		if wrapped.Job != nil {
+			return wrapped.Job, nil
+		}
+		job := new(nomad.Job)
+		if err := json.Unmarshal(trimmed, job); err != nil {
+			return nil, newTrekError(GenericError, err, "cannot parse job file %s", path)
+		}
+		return job, nil
+	}
+
+	job, err := client.Jobs().ParseHCL(string(content), true)
+	if err != nil {
+		return nil, apiError(err, "cannot parse job file %s", path)
+	}
+	return job, nil
+}
+
+// planJob asks the scheduler what submitting a job would change, without
+// changing anything
+func planJob(client *nomad.Client, job *nomad.Job) (*nomad.JobPlanResponse, error) {
+	options := &nomad.WriteOptions{}
+	if job.Namespace != nil {
+		options.Namespace = *job.Namespace
+	}
+	plan, _, err := client.Jobs().Plan(job, true, options)
+	if err != nil {
+		return nil, apiError(err, "cannot plan job %s", *job.ID)
+	}
+	return plan, nil
+}
+
+// planWriter renders plans, optionally using colors
+type planWriter struct {
+	w     io.Writer
+	color bool
+}
+
+func (p planWriter) colorize(color string, text string) string {
+	if !p.color || color == "" {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+func diffMarker(diffType string) (string, string) {
+	switch diffType {
+	case "Added":
+		return "+", ansiGreen
+	case "Deleted":
+		return "-", ansiRed
+	case "Edited":
+		return "+/-", ansiYellow
+	default:
+		return "", ""
+	}
+}
+
+func (p planWriter) diffLine(depth int, diffType string, text string) {
+	marker, color := diffMarker(diffType)
+	if marker != "" {
+		marker = p.colorize(color, marker) + " "
+	}
+	fmt.Fprintf(p.w, "%s%s%s\n", strings.Repeat("  ", depth), marker, text)
+}
+
+func annotations(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(values, ", "))
+}
+
+func (p planWriter) fields(depth int, fields []*nomad.FieldDiff) {
+	for _, field := range fields {
+		var value string
+		switch field.Type {
+		case "Added":
+			value = fmt.Sprintf("%q", field.New)
+		case "Deleted":
+			value = fmt.Sprintf("%q", field.Old)
+		case "Edited":
+			value = fmt.Sprintf("%q => %q", field.Old, field.New)
+		default:
+			continue
+		}
+		p.diffLine(depth, field.Type, fmt.Sprintf("%s: %s%s", field.Name, value, annotations(field.Annotations)))
+	}
+}
+
+func (p planWriter) objects(depth int, objects []*nomad.ObjectDiff) {
+	for _, object := range objects {
+		if object.Type == "None" {
+			continue
+		}
+		p.diffLine(depth, object.Type, fmt.Sprintf("%s {", object.Name))
+		p.fields(depth+1, object.Fields)
+		p.objects(depth+1, object.Objects)
+		fmt.Fprintf(p.w, "%s}\n", strings.Repeat("  ", depth))
+	}
+}
+
+func formatUpdates(updates map[string]uint64) string {
+	keys := make([]string, 0, len(updates))
+	for key := range updates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if updates[key] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", updates[key], key))
+		}
+	}
+	return annotations(parts)
+}
+
+func (p planWriter) diff(diff *nomad.JobDiff) {
+	p.diffLine(0, diff.Type, fmt.Sprintf("Job: %q", diff.ID))
+	p.fields(1, diff.Fields)
+	p.objects(1, diff.Objects)
+
+	for _, group := range diff.TaskGroups {
+		p.diffLine(1, group.Type, fmt.Sprintf("Task Group: %q%s", group.Name, formatUpdates(group.Updates)))
+		p.fields(2, group.Fields)
+		p.objects(2, group.Objects)
+
+		for _, task := range group.Tasks {
+			p.diffLine(2, task.Type, fmt.Sprintf("Task: %q%s", task.Name, annotations(task.Annotations)))
+			p.fields(3, task.Fields)
+			p.objects(3, task.Objects)
+		}
+	}
+}
+
+// writePlacementFailures explains why the allocations of a task group could
+// not be placed
+func writePlacementFailures(w io.Writer, indent string, group string, metric *nomad.AllocationMetric) {
+	noun := "allocation"
+	if metric.CoalescedFailures > 0 {
+		noun = "allocations"
+	}
+	fmt.Fprintf(w, "%sTask Group %q (failed to place %d %s):\n", indent, group, metric.CoalescedFailures+1, noun)
+	for _, reason := range placementFailureReasons(metric) {
+		fmt.Fprintf(w, "%s  * %s\n", indent, reason)
+	}
+}
+
+// placementFailureReasons explains, in plain sentences, why the scheduler
+// could not place the allocations of a task group
+func placementFailureReasons(metric *nomad.AllocationMetric) []string {
+	reasons := make([]string, 0)
+
+	if metric.NodesEvaluated == 0 {
+		reasons = append(reasons, "No nodes were eligible for evaluation")
+	}
+	for _, available := range sortedCounts(metric.NodesAvailable) {
+		if available.count == 0 {
+			reasons = append(reasons, fmt.Sprintf("No nodes are available in datacenter %q", available.key))
+		}
+	}
+	for _, filtered := range sortedCounts(metric.ClassFiltered) {
+		reasons = append(reasons, fmt.Sprintf("Class %q: %d nodes excluded by filter", filtered.key, filtered.count))
+	}
+	for _, filtered := range sortedCounts(metric.ConstraintFiltered) {
+		reasons = append(reasons, fmt.Sprintf("Constraint %q: %d nodes excluded by filter", filtered.key, filtered.count))
+	}
+	if metric.NodesExhausted > 0 {
+		reasons = append(reasons, fmt.Sprintf("Resources exhausted on %d nodes", metric.NodesExhausted))
+	}
+	for _, exhausted := range sortedCounts(metric.ClassExhausted) {
+		reasons = append(reasons, fmt.Sprintf("Class %q exhausted on %d nodes", exhausted.key, exhausted.count))
+	}
+	for _, exhausted := range sortedCounts(metric.DimensionExhausted) {
+		reasons = append(reasons, fmt.Sprintf("Dimension %q exhausted on %d nodes", exhausted.key, exhausted.count))
+	}
+	for _, quota := range metric.QuotaExhausted {
+		reasons = append(reasons, fmt.Sprintf("Quota limit hit %q", quota))
+	}
+
+	return reasons
+}
+
+type keyCount struct {
+	key   string
+	count int
+}
+
+func sortedCounts(counts map[string]int) []keyCount {
+	result := make([]keyCount, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, keyCount{key: key, count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key < result[j].key })
+	return result
+}
+
+// writePlan renders the diff of a plan, followed by the scheduler dry-run
+func writePlan(w io.Writer, plan *nomad.JobPlanResponse, color bool) {
+	p := planWriter{w: w, color: color}
+	if plan.Diff != nil {
+		p.diff(plan.Diff)
+	}
+
+	fmt.Fprintln(w, "\nScheduler dry-run:")
+	if len(plan.FailedTGAllocs) == 0 {
+		fmt.Fprintln(w, "- All tasks successfully allocated.")
+	} else {
+		fmt.Fprintln(w, p.colorize(ansiYellow, "- WARNING: Failed to place all allocations."))
+		groups := make([]string, 0, len(plan.FailedTGAllocs))
+		for group := range plan.FailedTGAllocs {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			writePlacementFailures(w, "  ", group, plan.FailedTGAllocs[group])
+		}
+	}
+
+	if plan.Annotations != nil && len(plan.Annotations.PreemptedAllocs) > 0 {
+		fmt.Fprintf(w, "- %d allocations would be preempted\n", len(plan.Annotations.PreemptedAllocs))
+	}
+	if !plan.NextPeriodicLaunch.IsZero() {
+		fmt.Fprintf(w, "- Next periodic launch: %s\n", plan.NextPeriodicLaunch.Format("2006-01-02 15:04:05 MST"))
+	}
+	if plan.Warnings != "" {
+		fmt.Fprintf(w, "\n%s\n", p.colorize(ansiYellow, "Job Warnings:\n"+strings.TrimSpace(plan.Warnings)))
+	}
+}
+
+// planViewState describes the plan panel
+type planViewState struct {
+	returnView string
+}
+
+// printPlan plans a local job file and prints the result
+func printPlan(trekState *trekStateType, trekOptions trekOptions) error {
+	job, err := parseJobFile(trekState.client, trekOptions.planFile)
+	if err != nil {
+		return err
+	}
+	plan, err := planJob(trekState.client, job)
+	if err != nil {
+		return err
+	}
+
+	if trekOptions.outputFormat != TextOutput {
+		return trekPrintOutput(os.Stdout, trekOptions.outputFormat, "", plan)
+	}
+	writePlan(os.Stdout, plan, term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == "")
+	return nil
+}
+
+// showPlan asks for a job file, and shows what submitting it would change to
+// the selected job
+func showPlan(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	selected := *trekState.CurrentJob().ID
+
+	return openPrompt(g, v, trekState, "Plan job file", func(g *gocui.Gui, trekState *trekStateType, value string) error {
+		job, err := parseJobFile(trekState.client, value)
+		if err != nil {
+			return reportActionError(g, trekState, err)
+		}
+		if *job.ID != selected {
+			return reportActionError(g, trekState, newTrekError(GenericError, nil, "%s defines job %s, not %s", value, *job.ID, selected))
+		}
+		plan, err := planJob(trekState.client, job)
+		if err != nil {
+			return reportActionError(g, trekState, err)
+		}
+
+		returnView := g.CurrentView().Name()
+		maxX, maxY := g.Size()
+		bounds := getBounds(maxX, maxY, 0, 1, 5)
+		view, err := g.SetView(planViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+		if err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+		view.Editable = false
+		view.Wrap = false
+		view.Title = fmt.Sprintf("Plan: %s (%s)", selected, value)
+		view.Clear()
+		view.SetOrigin(0, 0)
+		writePlan(view, plan, trekState.theme.colored())
+
+		trekState.plan = &planViewState{returnView: returnView}
+		_, err = g.SetCurrentView(planViewName)
+		return err
+	})
+}
+
+func closePlan(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	returnView := trekState.plan.returnView
+	trekState.plan = nil
+	if err := g.DeleteView(planViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView(returnView)
+	return err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+const promptViewName = "prompt"
+
+// promptCallback is called with the value entered in a prompt
+type promptCallback func(g *gocui.Gui, trekState *trekStateType, value string) error
+
+type promptState struct {
+	returnView string
+	onSubmit   promptCallback
+	onChange   promptCallback
+	onCancel   promptCallback
+}
+
+// openPrompt asks the user for a single line of input, and calls onSubmit
+// with it once Enter is pressed.  Esc cancels the prompt.
+func openPrompt(g *gocui.Gui, v *gocui.View, trekState *trekStateType, title string, onSubmit promptCallback) error {
+	maxX, maxY := g.Size()
+	view, err := g.SetView(promptViewName, maxX/2-30, maxY/2, maxX/2+30, maxY/2+2)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Title = title
+	view.Editable = true
+	view.Wrap = false
+	view.Clear()
+	view.SetOrigin(0, 0)
+	view.SetCursor(0, 0)
+
+	trekState.prompt = &promptState{returnView: v.Name(), onSubmit: onSubmit}
+	g.Cursor = true
+	_, err = g.SetCurrentView(promptViewName)
+	return err
+}
+
+// openIncrementalPrompt works like openPrompt, but also calls onChange with
+// the value being typed after every key, and onCancel when Esc is pressed
+func openIncrementalPrompt(g *gocui.Gui, v *gocui.View, trekState *trekStateType, title string, onChange promptCallback, onSubmit promptCallback, onCancel promptCallback) error {
+	if err := openPrompt(g, v, trekState, title, onSubmit); err != nil {
+		return err
+	}
+	trekState.prompt.onChange = onChange
+	trekState.prompt.onCancel = onCancel
+
+	view, err := g.View(promptViewName)
+	if err != nil {
+		return err
+	}
+	view.Editor = gocui.EditorFunc(func(view *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+		gocui.DefaultEditor.Edit(view, key, ch, mod)
+		if prompt := trekState.prompt; prompt != nil && prompt.onChange != nil {
+			prompt.onChange(g, trekState, strings.TrimSpace(view.Buffer()))
+		}
+	})
+	return nil
+}
+
+func closePrompt(g *gocui.Gui, trekState *trekStateType) (*promptState, error) {
+	prompt := trekState.prompt
+	trekState.prompt = nil
+	g.Cursor = false
+
+	if err := g.DeleteView(promptViewName); err != nil {
+		return nil, err
+	}
+	if prompt != nil {
+		if _, err := g.SetCurrentView(prompt.returnView); err != nil {
+			return nil, err
+		}
+	}
+	return prompt, nil
+}
+
+func submitPrompt(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	value := strings.TrimSpace(v.Buffer())
+	prompt, err := closePrompt(g, trekState)
+	if err != nil || prompt == nil {
+		return err
+	}
+	return prompt.onSubmit(g, trekState, value)
+}
+
+func cancelPrompt(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	prompt, err := closePrompt(g, trekState)
+	if err != nil || prompt == nil || prompt.onCancel == nil {
+		return err
+	}
+	return prompt.onCancel(g, trekState, "")
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	jsonSpec = "json"
+	hclSpec  = "hcl"
+
+	specViewName = "Spec"
+)
+
+// hclApproximateNotice heads the HCL rendering of a job
+const hclApproximateNotice = "# Approximate rendering of the job stored by the cluster: review it before\n# submitting it again.\n"
+
+// hclFieldNames maps the fields of the API job to the attributes and blocks of
+// the job specification when their names differ.  Entries scoped by the field
+// holding them ("ReservedPorts.Value") win over the others.
+var hclFieldNames = map[string]string{
+	"TaskGroups":          "group",
+	"Tasks":               "task",
+	"Constraints":         "constraint",
+	"Affinities":          "affinity",
+	"Spreads":             "spread",
+	"SpreadTarget":        "target",
+	"Services":            "service",
+	"Checks":              "check",
+	"Templates":           "template",
+	"Artifacts":           "artifact",
+	"Networks":            "network",
+	"ReservedPorts":       "port",
+	"DynamicPorts":        "port",
+	"Devices":             "device",
+	"Volumes":             "volume",
+	"VolumeMounts":        "volume_mount",
+	"RestartPolicy":       "restart",
+	"ReschedulePolicy":    "reschedule",
+	"LogConfig":           "logs",
+	"ParameterizedJob":    "parameterized",
+	"CSIPluginConfig":     "csi_plugin",
+	"ExposeConfig":        "expose",
+	"LTarget":             "attribute",
+	"RTarget":             "value",
+	"Operand":             "operator",
+	"MBits":               "mbits",
+	"MemoryMB":            "memory",
+	"DiskMB":              "disk",
+	"SizeMB":              "size",
+	"MaxFileSizeMB":       "max_file_size",
+	"PortLabel":           "port",
+	"TaskName":            "task",
+	"SourcePath":          "source",
+	"DestPath":            "destination",
+	"EmbeddedTmpl":        "data",
+	"LeftDelim":           "left_delimiter",
+	"RightDelim":          "right_delimiter",
+	"Envvars":             "env",
+	"GetterSource":        "source",
+	"GetterOptions":       "options",
+	"GetterMode":          "mode",
+	"RelativeDest":        "destination",
+	"Periodic.Spec":       "cron",
+	"ReservedPorts.Value": "static",
+}
+
+// hclLabeledBlocks maps the blocks labeled with one of their fields to that
+// field
+var hclLabeledBlocks = map[string]string{
+	"TaskGroups":    "Name",
+	"Tasks":         "Name",
+	"Devices":       "Name",
+	"ReservedPorts": "Label",
+	"DynamicPorts":  "Label",
+	"SpreadTarget":  "Value",
+}
+
+// hclKeyedBlocks are maps written as one block per entry, labeled with its key
+var hclKeyedBlocks = map[string]bool{
+	"Volumes": true,
+}
+
+// hclSkippedFields are set by the servers, and are not part of a
+// specification.  Entries are scoped by the field holding them, like
+// hclFieldNames, or apply everywhere.
+var hclSkippedFields = map[string]bool{
+	"Job.ID":                true,
+	"Job.Status":            true,
+	"Job.StatusDescription": true,
+	"Job.Stable":            true,
+	"Job.Version":           true,
+	"Job.SubmitTime":        true,
+	"Job.JobModifyIndex":    true,
+	"Job.Dispatched":        true,
+	"Job.Stop":              true,
+	"Job.ParentID":          true,
+	"Job.Payload":           true,
+	"Job.NomadTokenID":      true,
+	"Periodic.SpecType":     true,
+	"DynamicPorts.Value":    true,
+	"Volumes.Name":          true,
+	"Scaling.ID":            true,
+	"Scaling.Namespace":     true,
+	"Scaling.Target":        true,
+	"CreateIndex":           true,
+	"ModifyIndex":           true,
+}
+
+// hclFreeformFields hold user-defined keys, which are kept as is
+var hclFreeformFields = map[string]bool{
+	"Meta":          true,
+	"Env":           true,
+	"Config":        true,
+	"Header":        true,
+	"GetterOptions": true,
+	"Policy":        true,
+}
+
+// hclDurationFields hold durations, serialized as nanoseconds by the API
+var hclDurationFields = map[string]bool{
+	"Delay":                     true,
+	"Grace":                     true,
+	"HealthyDeadline":           true,
+	"Interval":                  true,
+	"KillTimeout":               true,
+	"MaxDelay":                  true,
+	"MinHealthyTime":            true,
+	"ProgressDeadline":          true,
+	"ShutdownDelay":             true,
+	"Splay":                     true,
+	"Stagger":                   true,
+	"StopAfterClientDisconnect": true,
+	"Timeout":                   true,
+	"VaultGrace":                true,
+}
+
+// formatJobSpec renders the specification of a job as JSON, or as HCL.  The
+// HCL output is an approximation meant to be read: fields trek doesn't know
+// keep their API names, and it may need some editing before being submitted
+// again.
+func formatJobSpec(job *nomad.Job, format string) (string, error) {
+	encoded, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return "", newTrekError(GenericError, err, "cannot encode job %s", *job.ID)
+	}
+
+	switch format {
+	case jsonSpec:
+		return string(encoded) + "\n", nil
+	case hclSpec:
+		var fields map[string]interface{}
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return "", newTrekError(GenericError, err, "cannot encode job %s", *job.ID)
+		}
+		var buffer bytes.Buffer
+		buffer.WriteString(hclApproximateNotice)
+		fmt.Fprintf(&buffer, "job %s {\n", strconv.Quote(*job.ID))
+		writeHCLFields(&buffer, fields, 1, "Job", false)
+		buffer.WriteString("}\n")
+		return buffer.String(), nil
+	default:
+		return "", newTrekError(GenericError, nil, "unknown spec format: %s", format)
+	}
+}
+
+// hclName turns the name of a field, held by the field parent, into the name
+// of an attribute or block.  User-defined keys are only quoted when needed.
+func hclName(parent string, field string, freeform bool) string {
+	if freeform {
+		for _, r := range field {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+				return strconv.Quote(field)
+			}
+		}
+		return field
+	}
+	if name, ok := hclFieldNames[parent+"."+field]; ok {
+		return name
+	}
+	if name, ok := hclFieldNames[field]; ok {
+		return name
+	}
+
+	var name strings.Builder
+	runes := []rune(field)
+	for index, r := range runes {
+		if unicode.IsUpper(r) {
+			lowerNext := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+			if index > 0 && (unicode.IsLower(runes[index-1]) || lowerNext) {
+				name.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
+
+// writeHCLFields writes the attributes, then the blocks of an object held by
+// the field parent.  Fields left to their zero value are omitted, unless they
+// are user-defined.
+func writeHCLFields(buffer *bytes.Buffer, fields map[string]interface{}, depth int, parent string, freeform bool) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, blocks := range []bool{false, true} {
+		for _, key := range keys {
+			value := fields[key]
+			if !freeform && (isSkippedHCLField(parent, key) || isZeroHCLValue(value)) {
+				continue
+			}
+			if isHCLBlock(value) == blocks {
+				writeHCLField(buffer, parent, key, value, depth, freeform)
+			}
+		}
+	}
+}
+
+func isSkippedHCLField(parent string, field string) bool {
+	return hclSkippedFields[parent+"."+field] || hclSkippedFields[field]
+}
+
+func isZeroHCLValue(value interface{}) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case bool:
+		return !value
+	case map[string]interface{}:
+		return len(value) == 0
+	case []interface{}:
+		return len(value) == 0
+	default:
+		return false
+	}
+}
+
+func isHCLBlock(value interface{}) bool {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		return len(value) > 0 && isHCLBlock(value[0])
+	default:
+		return false
+	}
+}
+
+func writeHCLField(buffer *bytes.Buffer, parent string, key string, value interface{}, depth int, freeform bool) {
+	indent := strings.Repeat("  ", depth)
+	name := hclName(parent, key, freeform)
+	labeledBy, labeled := hclLabeledBlocks[key]
+	labeled = labeled && !freeform
+	keyed := hclKeyedBlocks[key] && !freeform
+	freeform = freeform || hclFreeformFields[key]
+
+	switch value := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		if len(value) == 0 {
+			return
+		}
+		if keyed {
+			labels := make([]string, 0, len(value))
+			for label := range value {
+				labels = append(labels, label)
+			}
+			sort.Strings(labels)
+			for _, label := range labels {
+				fields, ok := value[label].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				fmt.Fprintf(buffer, "%s%s %s {\n", indent, name, strconv.Quote(label))
+				writeHCLFields(buffer, fields, depth+1, key, freeform)
+				fmt.Fprintf(buffer, "%s}\n", indent)
+			}
+			return
+		}
+		fmt.Fprintf(buffer, "%s%s {\n", indent, name)
+		writeHCLFields(buffer, value, depth+1, key, freeform)
+		fmt.Fprintf(buffer, "%s}\n", indent)
+	case []interface{}:
+		if len(value) == 0 {
+			return
+		}
+		if !isHCLBlock(value) {
+			fmt.Fprintf(buffer, "%s%s = %s\n", indent, name, hclValue(key, value, freeform))
+			return
+		}
+		for _, item := range value {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			label := ""
+			if labeled {
+				label = " " + strconv.Quote(fmt.Sprint(fields[labeledBy]))
+				delete(fields, labeledBy)
+			}
+			fmt.Fprintf(buffer, "%s%s%s {\n", indent, name, label)
+			writeHCLFields(buffer, fields, depth+1, key, freeform)
+			fmt.Fprintf(buffer, "%s}\n", indent)
+		}
+	default:
+		fmt.Fprintf(buffer, "%s%s = %s\n", indent, name, hclValue(key, value, freeform))
+	}
+}
+
+func hclValue(key string, value interface{}, freeform bool) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case float64:
+		if hclDurationFields[key] && !freeform {
+			return strconv.Quote(time.Duration(value).String())
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, hclValue(key, item, freeform))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// specViewState describes the spec panel
+type specViewState struct {
+	format     string
+	returnView string
+}
+
+// showSpec opens a panel with the specification of the selected job
+func showSpec(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	maxX, maxY := g.Size()
+	bounds := getBounds(maxX, maxY, 0, 1, 5)
+	view, err := g.SetView(specViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Editable = false
+	view.Wrap = false
+
+	trekState.spec = &specViewState{format: jsonSpec, returnView: v.Name()}
+	renderSpec(view, trekState)
+
+	_, err = g.SetCurrentView(specViewName)
+	return err
+}
+
+func renderSpec(v *gocui.View, trekState *trekStateType) {
+	job := trekState.CurrentJob()
+	format := trekState.spec.format
+	v.Clear()
+	v.SetOrigin(0, 0)
+	v.Title = fmt.Sprintf("Spec: %s (%s, h: toggle json/hcl)", *job.ID, format)
+
+	spec, err := formatJobSpec(&job, format)
+	if err != nil {
+		fmt.Fprintln(v, err)
+		return
+	}
+	fmt.Fprint(v, spec)
+}
+
+// toggleSpecFormat switches the spec panel between JSON and HCL
+func toggleSpecFormat(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	if trekState.spec.format == jsonSpec {
+		trekState.spec.format = hclSpec
+	} else {
+		trekState.spec.format = jsonSpec
+	}
+	renderSpec(v, trekState)
+	return nil
+}
+
+func closeSpec(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	returnView := trekState.spec.returnView
+	trekState.spec = nil
+	if err := g.DeleteView(specViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView(returnView)
+	return err
+}
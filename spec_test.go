@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+func TestHCLName(t *testing.T) {
+	tests := []struct {
+		parent   string
+		field    string
+		freeform bool
+		expected string
+	}{
+		{"Job", "Datacenters", false, "datacenters"},
+		{"Job", "AllAtOnce", false, "all_at_once"},
+		{"Job", "TaskGroups", false, "group"},
+		{"Resources", "CPU", false, "cpu"},
+		{"Resources", "MemoryMB", false, "memory"},
+		{"Networks", "MBits", false, "mbits"},
+		{"Networks", "DynamicPorts", false, "port"},
+		{"ReservedPorts", "Value", false, "static"},
+		{"SpreadTarget", "Value", false, "value"},
+		{"Periodic", "Spec", false, "cron"},
+		{"Checks", "TLSSkipVerify", false, "tls_skip_verify"},
+		{"Checks", "GRPCUseTLS", false, "grpc_use_tls"},
+		{"Checks", "PortLabel", false, "port"},
+		{"Templates", "EmbeddedTmpl", false, "data"},
+		{"Templates", "LeftDelim", false, "left_delimiter"},
+		{"Artifacts", "GetterSource", false, "source"},
+		{"EphemeralDisk", "SizeMB", false, "size"},
+		{"Meta", "owner_team", true, "owner_team"},
+		{"Meta", "MixedCase", true, "MixedCase"},
+		{"Env", "app.version", true, `"app.version"`},
+	}
+	for _, test := range tests {
+		if name := hclName(test.parent, test.field, test.freeform); name != test.expected {
+			t.Errorf("hclName(%q, %q, %v) = %q, expected %q", test.parent, test.field, test.freeform, name, test.expected)
+		}
+	}
+}
+
+func stringToPtr(value string) *string { return &value }
+func intToPtr(value int) *int          { return &value }
+func boolToPtr(value bool) *bool       { return &value }
+func durationToPtr(value time.Duration) *time.Duration {
+	return &value
+}
+
+// specJob is a job using the blocks whose HCL names differ from the API ones
+func specJob() *nomad.Job {
+	job := nomad.NewServiceJob("web", "web", "global", 50)
+	job.Datacenters = []string{"dc1"}
+	job.Status = stringToPtr("running")
+	job.Meta = map[string]string{"owner.team": "platform"}
+
+	task := nomad.NewTask("server", "docker")
+	task.Config = map[string]interface{}{"image": "nginx:1.19", "port_map": []interface{}{map[string]interface{}{"http": 80}}}
+	task.Env = map[string]string{"LOG_LEVEL": "debug"}
+	task.Resources = &nomad.Resources{CPU: intToPtr(500), MemoryMB: intToPtr(256)}
+	task.KillTimeout = durationToPtr(30 * time.Second)
+	task.Templates = []*nomad.Template{{
+		EmbeddedTmpl: stringToPtr("port={{ env \"NOMAD_PORT_http\" }}"),
+		DestPath:     stringToPtr("local/app.env"),
+		Envvars:      boolToPtr(true),
+	}}
+	task.Artifacts = []*nomad.TaskArtifact{{
+		GetterSource:  stringToPtr("https://example.com/app.tar.gz"),
+		GetterOptions: map[string]string{"checksum": "sha256:abcd"},
+	}}
+	task.VolumeMounts = []*nomad.VolumeMount{{Volume: stringToPtr("data"), Destination: stringToPtr("/data")}}
+	task.LogConfig = &nomad.LogConfig{MaxFiles: intToPtr(5), MaxFileSizeMB: intToPtr(20)}
+
+	group := nomad.NewTaskGroup("frontend", 3)
+	group.Networks = []*nomad.NetworkResource{{
+		MBits:         intToPtr(10),
+		ReservedPorts: []nomad.Port{{Label: "admin", Value: 9000}},
+		DynamicPorts:  []nomad.Port{{Label: "http", To: 80}},
+	}}
+	group.Volumes = map[string]*nomad.VolumeRequest{"data": {Name: "data", Type: "host", Source: "web-data"}}
+	group.EphemeralDisk = &nomad.EphemeralDisk{SizeMB: intToPtr(300)}
+	group.Spreads = []*nomad.Spread{nomad.NewSpread("${node.datacenter}", 100, []*nomad.SpreadTarget{nomad.NewSpreadTarget("dc1", 70)})}
+	group.Services = []*nomad.Service{{
+		Name:      "web",
+		PortLabel: "http",
+		Checks:    []nomad.ServiceCheck{{Type: "http", Path: "/health", PortLabel: "http", Interval: 10 * time.Second, Timeout: 2 * time.Second}},
+	}}
+	group.AddTask(task)
+	job.AddTaskGroup(group)
+	job.Periodic = &nomad.PeriodicConfig{Spec: stringToPtr("*/5 * * * *"), SpecType: stringToPtr("cron")}
+	return job
+}
+
+func TestFormatJobSpecHCL(t *testing.T) {
+	spec, err := formatJobSpec(specJob(), hclSpec)
+	if err != nil {
+		t.Fatalf("formatJobSpec: %v", err)
+	}
+	if !strings.HasPrefix(spec, hclApproximateNotice+"job \"web\" {\n") {
+		t.Errorf("the spec doesn't start with the notice and the job block:\n%s", spec)
+	}
+
+	expected := []string{
+		`  datacenters = ["dc1"]`,
+		`    "owner.team" = "platform"`,
+		`  group "frontend" {`,
+		`    count = 3`,
+		`      mbits = 10`,
+		"      port \"admin\" {\n        to = 0\n        static = 9000\n",
+		"      port \"http\" {\n        to = 80\n      }",
+		"    volume \"data\" {\n      source = \"web-data\"\n      type = \"host\"\n    }",
+		"    ephemeral_disk {\n      size = 300\n",
+		"      target \"dc1\" {\n        percent = 70\n",
+		`      port = "http"`,
+		`        interval = "10s"`,
+		`        path = "/health"`,
+		`    task "server" {`,
+		`        image = "nginx:1.19"`,
+		`        LOG_LEVEL = "debug"`,
+		`      kill_timeout = "30s"`,
+		`        memory = 256`,
+		`        cpu = 500`,
+		`        data = "port={{ env \"NOMAD_PORT_http\" }}"`,
+		`        destination = "local/app.env"`,
+		`        env = true`,
+		`        source = "https://example.com/app.tar.gz"`,
+		`          checksum = "sha256:abcd"`,
+		"      volume_mount {\n        destination = \"/data\"\n        volume = \"data\"\n",
+		"      logs {\n        max_file_size = 20\n        max_files = 5\n",
+		"  periodic {\n    cron = \"*/5 * * * *\"\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(spec, line) {
+			t.Errorf("the spec doesn't contain %q:\n%s", line, spec)
+		}
+	}
+
+	for _, name := range []string{"dynamic_ports", "reserved_ports", "memory_mb", "m_bits", "size_mb", "spread_target",
+		"embedded_tmpl", "dest_path", "envvars", "getter_source", "port_label", "spec_type", "status", "label =", "name = \"data\""} {
+		if strings.Contains(spec, name) {
+			t.Errorf("the spec contains %q:\n%s", name, spec)
+		}
+	}
+}
+
+func TestFormatJobSpecJSON(t *testing.T) {
+	spec, err := formatJobSpec(specJob(), jsonSpec)
+	if err != nil {
+		t.Fatalf("formatJobSpec: %v", err)
+	}
+	if !strings.Contains(spec, `"DynamicPorts": [`) || strings.HasPrefix(spec, "#") {
+		t.Errorf("the JSON spec isn't the job as the API returns it:\n%s", spec)
+	}
+
+	if _, err := formatJobSpec(specJob(), "yaml"); err == nil {
+		t.Errorf("formatJobSpec with an unknown format: expected an error")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	// statsPollInterval is how often the stats panel samples resource usage
+	statsPollInterval = 2 * time.Second
+
+	// statsHistorySize is how many samples per task the stats panel keeps
+	statsHistorySize = 60
+
+	statsViewName = "Stats"
+)
+
+// sparkTicks are the characters sparklines are drawn with, from lowest to
+// highest
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws a series of values, scaled to the highest one
+func sparkline(values []float64) string {
+	var max float64
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+
+	line := make([]rune, 0, len(values))
+	for _, value := range values {
+		tick := 0
+		if max > 0 {
+			tick = int(value / max * float64(len(sparkTicks)-1))
+		}
+		line = append(line, sparkTicks[tick])
+	}
+	return string(line)
+}
+
+// formatBytes describes a size using its largest binary unit
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func fetchStats(client *nomad.Client, alloc *nomad.Allocation) (*nomad.AllocResourceUsage, error) {
+	usage, err := client.Allocations().Stats(alloc, &nomad.QueryOptions{})
+	if err != nil {
+		return nil, apiError(err, "cannot fetch resource usage of allocation %s", alloc.ID)
+	}
+	return usage, nil
+}
+
+// taskStatsHistory keeps the latest resource usage samples of a task
+type taskStatsHistory struct {
+	last      trekTaskStats
+	cpu       []float64
+	memory    []float64
+	throttled []float64
+}
+
+func appendSample(values []float64, value float64) []float64 {
+	values = append(values, value)
+	if len(values) > statsHistorySize {
+		values = values[len(values)-statsHistorySize:]
+	}
+	return values
+}
+
+// record adds a sample to the history.  Throttling is recorded as the number
+// of periods throttled since the previous sample.
+func (history *taskStatsHistory) record(stats trekTaskStats) {
+	var throttled float64
+	if history.last.Name != "" && stats.ThrottledPeriods >= history.last.ThrottledPeriods {
+		throttled = float64(stats.ThrottledPeriods - history.last.ThrottledPeriods)
+	}
+
+	history.cpu = appendSample(history.cpu, stats.CPUPercent)
+	history.memory = appendSample(history.memory, float64(stats.MemoryRSS))
+	history.throttled = appendSample(history.throttled, throttled)
+	history.last = stats
+}
+
+// statsViewState describes the stats panel
+type statsViewState struct {
+	alloc      nomad.Allocation
+	tasks      []string
+	history    map[string]*taskStatsHistory
+	err        error
+	returnView string
+}
+
+func (stats *statsViewState) record(usage *nomad.AllocResourceUsage, err error) {
+	stats.err = err
+	if err != nil {
+		return
+	}
+
+	for _, task := range buildTaskStats(usage, "") {
+		history, ok := stats.history[task.Name]
+		if !ok {
+			history = &taskStatsHistory{}
+			stats.history[task.Name] = history
+			stats.tasks = append(stats.tasks, task.Name)
+		}
+		history.record(task)
+	}
+}
+
+func renderStats(v *gocui.View, trekState *trekStateType) {
+	stats := trekState.stats
+	v.Clear()
+	v.Title = fmt.Sprintf("Stats: %s (every %s)", shortID(stats.alloc.ID), statsPollInterval)
+
+	if stats.err != nil {
+		fmt.Fprintf(v, "%s\n\n", stats.err)
+	}
+	for _, task := range stats.tasks {
+		history := stats.history[task]
+		last := history.last
+		throttled := history.throttled[len(history.throttled)-1]
+
+		fmt.Fprintf(v, "%s\n", task)
+		fmt.Fprintf(v, "  CPU       %9.1f%% %12s  %s\n", last.CPUPercent, fmt.Sprintf("%.0f MHz", last.CPUTotalTicks), sparkline(history.cpu))
+		fmt.Fprintf(v, "  RSS       %10s %12s  %s\n", formatBytes(last.MemoryRSS), "", sparkline(history.memory))
+		fmt.Fprintf(v, "  Throttled %10d %12s  %s\n\n", last.ThrottledPeriods, fmt.Sprintf("+%.0f", throttled), sparkline(history.throttled))
+	}
+}
+
+// showStats opens the stats panel on the selected allocation, sampling its
+// resource usage until the panel is closed
+func showStats(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	alloc, err := trekState.CurrentAllocation()
+	if err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+
+	maxX, maxY := g.Size()
+	bounds := getBounds(maxX, maxY, 0, 1, 5)
+	view, err := g.SetView(statsViewName, bounds.startX, bounds.startY, bounds.endX, bounds.endY)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	view.Editable = false
+	view.Wrap = false
+
+	trekState.stats = &statsViewState{
+		alloc:      alloc.allocation,
+		history:    make(map[string]*taskStatsHistory),
+		returnView: v.Name(),
+	}
+	view.Clear()
+	view.Title = "Stats"
+
+	client := trekState.client
+	stop := trekState.addWatcher(statsViewName)
+	go func() {
+		ticker := time.NewTicker(statsPollInterval)
+		defer ticker.Stop()
+		for {
+			usage, err := fetchStats(client, &alloc.allocation)
+			g.Update(func(g *gocui.Gui) error {
+				select {
+				case <-stop:
+					return nil
+				default:
+				}
+				trekState.stats.record(usage, err)
+				if v, err := g.View(statsViewName); err == nil {
+					renderStats(v, trekState)
+				}
+				return nil
+			})
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	_, err = g.SetCurrentView(statsViewName)
+	return err
+}
+
+func closeStats(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	trekState.stopWatch(statsViewName)
+	returnView := trekState.stats.returnView
+	trekState.stats = nil
+	if err := g.DeleteView(statsViewName); err != nil {
+		return err
+	}
+	_, err := g.SetCurrentView(returnView)
+	return err
+}
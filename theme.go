@@ -0,0 +1,358 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	colorTheme        = "color"
+	monochromeTheme   = "monochrome"
+	highContrastTheme = "high-contrast"
+)
+
+// colorPair sets the foreground and background colors of a panel
+type colorPair struct {
+	Foreground string
+	Background string
+}
+
+// themeConfig sets the colors of the UI, as set in .trek.rc.  A color is a
+// name (black, red, green, yellow, blue, magenta, cyan, white or default) or
+// a number from 0 to 255, followed by styles: "red+bold", "244+underline".
+type themeConfig struct {
+	// Mode picks the base theme: color (the default), monochrome or
+	// high-contrast.  The entries below override its colors.
+	Mode string
+	// Selection is the line under the cursor
+	Selection *colorPair
+	// Title is the title of the application, Menu is the menu bar next to it
+	Title *colorPair
+	Menu  *colorPair
+	// Frame and FocusedFrame color the borders and titles of the panels
+	Frame        string
+	FocusedFrame string
+	// Status maps a status (running, pending, dead, failed, lost...) to the
+	// color it is printed with
+	Status map[string]string
+}
+
+// themePresets are the base themes
+var themePresets = map[string]themeConfig{
+	colorTheme: {
+		Selection:    &colorPair{Foreground: "black", Background: "green"},
+		Title:        &colorPair{Foreground: "black", Background: "blue"},
+		Menu:         &colorPair{Foreground: "black", Background: "green"},
+		Frame:        "default",
+		FocusedFrame: "green",
+		Status: map[string]string{
+			"running":      "green",
+			"successful":   "green",
+			"ready":        "green",
+			"pending":      "yellow",
+			"initializing": "yellow",
+			"blocked":      "yellow",
+			"paused":       "yellow",
+			"dead":         "blue",
+			"complete":     "blue",
+			"cancelled":    "blue",
+			"canceled":     "blue",
+			"failed":       "red",
+			"down":         "red",
+			"lost":         "magenta",
+			"read-only":    "red",
+			"confirm":      "yellow",
+		},
+	},
+	// monochrome relies on styles only, for terminals without colors
+	monochromeTheme: {
+		Selection:    &colorPair{Foreground: "default+reverse", Background: "default"},
+		Title:        &colorPair{Foreground: "default+reverse+bold", Background: "default"},
+		Menu:         &colorPair{Foreground: "default+reverse", Background: "default"},
+		Frame:        "default",
+		FocusedFrame: "default+bold",
+		Status: map[string]string{
+			"running":   "default+bold",
+			"pending":   "default+underline",
+			"failed":    "default+bold+underline",
+			"down":      "default+bold+underline",
+			"lost":      "default+bold+underline",
+			"read-only": "default+bold",
+			"confirm":   "default+underline",
+		},
+	},
+	// high-contrast uses bold, bright colors on the darkest background
+	highContrastTheme: {
+		Selection:    &colorPair{Foreground: "black+bold", Background: "yellow"},
+		Title:        &colorPair{Foreground: "black+bold", Background: "white"},
+		Menu:         &colorPair{Foreground: "black+bold", Background: "white"},
+		Frame:        "white",
+		FocusedFrame: "yellow+bold",
+		Status: map[string]string{
+			"running":      "green+bold",
+			"successful":   "green+bold",
+			"ready":        "green+bold",
+			"pending":      "yellow+bold",
+			"initializing": "yellow+bold",
+			"blocked":      "yellow+bold",
+			"paused":       "yellow+bold",
+			"dead":         "cyan+bold",
+			"complete":     "cyan+bold",
+			"cancelled":    "cyan+bold",
+			"canceled":     "cyan+bold",
+			"failed":       "red+bold+underline",
+			"down":         "red+bold+underline",
+			"lost":         "magenta+bold+underline",
+			"read-only":    "red+bold",
+			"confirm":      "yellow+bold",
+		},
+	},
+}
+
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// themeColor is a color of the terminal along with its styles
+type themeColor struct {
+	// index is the number of the color, or -1 for the default one
+	index     int
+	bold      bool
+	underline bool
+	reverse   bool
+}
+
+func parseColor(value string) (themeColor, error) {
+	color := themeColor{index: -1}
+	for _, token := range strings.Split(strings.ToLower(value), "+") {
+		token = strings.TrimSpace(token)
+		switch token {
+		case "", "default":
+		case "bold":
+			color.bold = true
+		case "underline":
+			color.underline = true
+		case "reverse":
+			color.reverse = true
+		default:
+			index, err := strconv.Atoi(token)
+			if err != nil {
+				index = -1
+				for named, name := range colorNames {
+					if name == token {
+						index = named
+					}
+				}
+			}
+			if index < 0 || index > 255 {
+				return themeColor{}, newTrekError(GenericError, nil,
+					"unknown color %q (expected default, %s, a number from 0 to 255, bold, underline or reverse)",
+					token, strings.Join(colorNames, ", "))
+			}
+			color.index = index
+		}
+	}
+	return color, nil
+}
+
+// attribute is the gocui attribute of a color
+func (color themeColor) attribute() gocui.Attribute {
+	attribute := gocui.ColorDefault
+	if color.index >= 0 {
+		attribute = gocui.Attribute(color.index + 1)
+	}
+	if color.bold {
+		attribute |= gocui.AttrBold
+	}
+	if color.underline {
+		attribute |= gocui.AttrUnderline
+	}
+	if color.reverse {
+		attribute |= gocui.AttrReverse
+	}
+	return attribute
+}
+
+// ansi is the escape sequence printing text in a color, as read by the
+// panels.  In 256-color mode, gocui only reads the styles following the color.
+func (color themeColor) ansi() string {
+	params := make([]string, 0)
+	switch {
+	case color.index >= 8:
+		params = append(params, "38", "5", strconv.Itoa(color.index))
+	case color.index >= 0:
+		params = append(params, strconv.Itoa(30+color.index))
+	}
+	if color.bold {
+		params = append(params, "1")
+	}
+	if color.underline {
+		params = append(params, "4")
+	}
+	if color.reverse {
+		params = append(params, "7")
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// ansiSequence matches the escape sequences setting colors
+var ansiSequence = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// stripColors removes the escape sequences setting colors from a line
+func stripColors(line string) string {
+	return ansiSequence.ReplaceAllString(line, "")
+}
+
+type themePair struct {
+	foreground themeColor
+	background themeColor
+}
+
+// theme holds the colors of the UI
+type theme struct {
+	mode         string
+	selection    themePair
+	title        themePair
+	menu         themePair
+	frame        themeColor
+	focusedFrame themeColor
+	status       map[string]themeColor
+	// output is the color mode of the terminal: 256 colors are only enabled
+	// when the theme uses them
+	output gocui.OutputMode
+}
+
+// resolveTheme applies the colors set in .trek.rc to a base theme.  The mode
+// given on the command line wins over the one of .trek.rc, and NO_COLOR picks
+// the monochrome theme when neither sets one.
+func resolveTheme(config *themeConfig, mode string) (*theme, error) {
+	if config == nil {
+		config = &themeConfig{}
+	}
+	if mode == "" {
+		mode = config.Mode
+	}
+	if mode == "" {
+		mode = colorTheme
+		if os.Getenv("NO_COLOR") != "" {
+			mode = monochromeTheme
+		}
+	}
+
+	preset, ok := themePresets[mode]
+	if !ok {
+		return nil, newTrekError(GenericError, nil, "unknown theme %q (expected %s, %s or %s)",
+			mode, colorTheme, monochromeTheme, highContrastTheme)
+	}
+
+	resolved := &theme{mode: mode, status: make(map[string]themeColor), output: gocui.OutputNormal}
+	var err error
+	if resolved.selection, err = resolvePair("Selection", preset.Selection, config.Selection); err != nil {
+		return nil, err
+	}
+	if resolved.title, err = resolvePair("Title", preset.Title, config.Title); err != nil {
+		return nil, err
+	}
+	if resolved.menu, err = resolvePair("Menu", preset.Menu, config.Menu); err != nil {
+		return nil, err
+	}
+	if resolved.frame, err = resolveColor("Frame", coalesce(config.Frame, preset.Frame)); err != nil {
+		return nil, err
+	}
+	if resolved.focusedFrame, err = resolveColor("FocusedFrame", coalesce(config.FocusedFrame, preset.FocusedFrame)); err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string)
+	for status, color := range preset.Status {
+		statuses[status] = color
+	}
+	for status, color := range config.Status {
+		statuses[strings.ToLower(status)] = color
+	}
+	for status, value := range statuses {
+		if resolved.status[status], err = resolveColor("Status "+status, value); err != nil {
+			return nil, err
+		}
+	}
+
+	colors := []themeColor{resolved.frame, resolved.focusedFrame,
+		resolved.selection.foreground, resolved.selection.background,
+		resolved.title.foreground, resolved.title.background,
+		resolved.menu.foreground, resolved.menu.background}
+	for _, color := range resolved.status {
+		colors = append(colors, color)
+	}
+	for _, color := range colors {
+		if color.index >= 8 {
+			resolved.output = gocui.Output256
+		}
+	}
+
+	return resolved, nil
+}
+
+func resolveColor(entry string, value string) (themeColor, error) {
+	color, err := parseColor(value)
+	if err != nil {
+		return themeColor{}, newTrekError(GenericError, err, "invalid color for %s", entry)
+	}
+	return color, nil
+}
+
+func resolvePair(entry string, preset *colorPair, config *colorPair) (themePair, error) {
+	pair := *preset
+	if config != nil {
+		pair.Foreground = coalesce(config.Foreground, pair.Foreground)
+		pair.Background = coalesce(config.Background, pair.Background)
+	}
+
+	var resolved themePair
+	var err error
+	if resolved.foreground, err = resolveColor(entry+" foreground", pair.Foreground); err != nil {
+		return resolved, err
+	}
+	resolved.background, err = resolveColor(entry+" background", pair.Background)
+	return resolved, err
+}
+
+// colored tells whether the theme prints colors, rather than styles only
+func (theme *theme) colored() bool {
+	return theme.mode != monochromeTheme
+}
+
+// apply sets the colors of the frames
+func (theme *theme) apply(g *gocui.Gui) {
+	g.Highlight = true
+	g.FgColor = theme.frame.attribute()
+	g.SelFgColor = theme.focusedFrame.attribute()
+}
+
+// highlight sets the colors of the line under the cursor of a panel
+func (theme *theme) highlight(view *gocui.View) {
+	view.SelFgColor = theme.selection.foreground.attribute()
+	view.SelBgColor = theme.selection.background.attribute()
+}
+
+// paint prints text in the color of a status, when the palette has one
+func (theme *theme) paint(status string, text string) string {
+	color, ok := theme.status[strings.ToLower(status)]
+	if !ok {
+		return text
+	}
+	sequence := color.ansi()
+	if sequence == "" {
+		return text
+	}
+	return sequence + text + ansiReset
+}
+
+// statusText prints a status in its color
+func (theme *theme) statusText(status string) string {
+	return theme.paint(status, status)
+}
@@ -1,19 +1,23 @@
 package main
 
 import (
-	"log"
+	"sort"
+	"time"
 
 	"github.com/hashicorp/nomad/api"
 	nomad "github.com/hashicorp/nomad/api"
 )
 
-func buildNetwork(networks []*api.NetworkResource) trekCommandNetwork {
+func buildNetwork(networks []*api.NetworkResource) (trekCommandNetwork, error) {
 	network := trekCommandNetwork{}
 	network.DynamicPorts = make([]trekCommandPort, 0)
 	network.ReservedPorts = make([]trekCommandPort, 0)
 
 	if len(networks) > 1 {
-		log.Panicf("Found more than one network.  Exiting...")
+		return network, newTrekError(AmbiguousSelectionError, nil, "found more than one network")
+	}
+	if len(networks) == 0 {
+		return network, nil
 	}
 	for _, reservedPort := range networks[0].ReservedPorts {
 		network.ReservedPorts = append(network.ReservedPorts, trekCommandPort{Name: reservedPort.Label, Number: reservedPort.Value})
@@ -22,7 +26,7 @@ func buildNetwork(networks []*api.NetworkResource) trekCommandNetwork {
 		network.DynamicPorts = append(network.DynamicPorts, trekCommandPort{Name: dynPort.Label, Number: dynPort.Value})
 	}
 
-	return network
+	return network, nil
 }
 
 func buildEnv(env map[string]string) trekCommandEnvironment {
@@ -47,7 +51,13 @@ func buildAllocations(allocs []nomad.Allocation) []trekAllocation {
 	result := make([]trekAllocation, 0)
 
 	for _, alloc := range allocs {
-		result = append(result, trekAllocation{Name: alloc.Name})
+		result = append(result, trekAllocation{
+			ID:            alloc.ID,
+			Name:          alloc.Name,
+			ClientStatus:  alloc.ClientStatus,
+			DesiredStatus: alloc.DesiredStatus,
+			CreateTime:    time.Unix(0, alloc.CreateTime),
+		})
 	}
 
 	return result
@@ -63,12 +73,252 @@ func buildTaskGroups(tgs []*nomad.TaskGroup) []trekTaskGroup {
 	return result
 }
 
-func buildJobs(jobs []nomad.Job) []trekJob {
+func buildFiles(files []*nomad.AllocFileInfo) []trekFile {
+	result := make([]trekFile, 0)
+	for _, file := range files {
+		result = append(result, trekFile{
+			Name:     file.Name,
+			IsDir:    file.IsDir,
+			Size:     file.Size,
+			FileMode: file.FileMode,
+			ModTime:  file.ModTime,
+		})
+	}
+	return result
+}
+
+// buildTaskStats maps the resource usage of the tasks of an allocation,
+// keeping only the given task unless it's empty
+func buildTaskStats(usage *nomad.AllocResourceUsage, task string) []trekTaskStats {
+	result := make([]trekTaskStats, 0)
+	for name, taskUsage := range usage.Tasks {
+		if task != "" && name != task {
+			continue
+		}
+		stats := trekTaskStats{Name: name}
+		if taskUsage.ResourceUsage != nil {
+			if cpu := taskUsage.ResourceUsage.CpuStats; cpu != nil {
+				stats.CPUPercent = cpu.Percent
+				stats.CPUTotalTicks = cpu.TotalTicks
+				stats.ThrottledPeriods = cpu.ThrottledPeriods
+				stats.ThrottledTime = cpu.ThrottledTime
+			}
+			if memory := taskUsage.ResourceUsage.MemoryStats; memory != nil {
+				stats.MemoryRSS = memory.RSS
+				stats.MemoryCache = memory.Cache
+				stats.MemorySwap = memory.Swap
+			}
+		}
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func buildNodes(nodes []*nomad.NodeListStub) []trekNodeStub {
+	result := make([]trekNodeStub, 0)
+	for _, node := range nodes {
+		result = append(result, trekNodeStub{
+			ID:                    node.ID,
+			Name:                  node.Name,
+			Address:               node.Address,
+			Datacenter:            node.Datacenter,
+			NodeClass:             node.NodeClass,
+			Version:               node.Version,
+			Status:                node.Status,
+			SchedulingEligibility: node.SchedulingEligibility,
+			Drain:                 node.Drain,
+		})
+	}
+	return result
+}
+
+func buildNodeDetails(node *nomad.Node, allocations []*nomad.Allocation) nodeFormatProvider {
+	details := nodeFormatProvider{
+		ID:                    node.ID,
+		Name:                  node.Name,
+		Address:               node.HTTPAddr,
+		Datacenter:            node.Datacenter,
+		NodeClass:             node.NodeClass,
+		Status:                node.Status,
+		SchedulingEligibility: node.SchedulingEligibility,
+		Drain:                 node.Drain,
+		Drivers:               make([]trekNodeDriver, 0),
+		Attributes:            node.Attributes,
+		Meta:                  node.Meta,
+		Allocations:           make([]trekNodeAllocation, 0),
+	}
+
+	if node.NodeResources != nil {
+		details.Resources = trekNodeResources{
+			CPU:      node.NodeResources.Cpu.CpuShares,
+			MemoryMB: node.NodeResources.Memory.MemoryMB,
+			DiskMB:   node.NodeResources.Disk.DiskMB,
+		}
+	} else if node.Resources != nil {
+		if node.Resources.CPU != nil {
+			details.Resources.CPU = int64(*node.Resources.CPU)
+		}
+		if node.Resources.MemoryMB != nil {
+			details.Resources.MemoryMB = int64(*node.Resources.MemoryMB)
+		}
+		if node.Resources.DiskMB != nil {
+			details.Resources.DiskMB = int64(*node.Resources.DiskMB)
+		}
+	}
+
+	for name, driver := range node.Drivers {
+		details.Drivers = append(details.Drivers, trekNodeDriver{
+			Name:     name,
+			Detected: driver.Detected,
+			Healthy:  driver.Healthy,
+		})
+	}
+	sort.Slice(details.Drivers, func(i, j int) bool { return details.Drivers[i].Name < details.Drivers[j].Name })
+
+	for _, alloc := range allocations {
+		details.Allocations = append(details.Allocations, trekNodeAllocation{
+			ID:            alloc.ID,
+			Name:          alloc.Name,
+			JobID:         alloc.JobID,
+			TaskGroup:     alloc.TaskGroup,
+			ClientStatus:  alloc.ClientStatus,
+			DesiredStatus: alloc.DesiredStatus,
+		})
+	}
+	sort.Slice(details.Allocations, func(i, j int) bool { return details.Allocations[i].Name < details.Allocations[j].Name })
+
+	return details
+}
+
+func buildDeployment(deployment *nomad.Deployment) deploymentFormatProvider {
+	details := deploymentFormatProvider{
+		ID:                deployment.ID,
+		JobID:             deployment.JobID,
+		JobVersion:        deployment.JobVersion,
+		Status:            deployment.Status,
+		StatusDescription: deployment.StatusDescription,
+		TaskGroups:        make([]trekDeploymentGroup, 0),
+	}
+
+	for name, state := range deployment.TaskGroups {
+		details.TaskGroups = append(details.TaskGroups, trekDeploymentGroup{
+			Name:              name,
+			Desired:           state.DesiredTotal,
+			Placed:            state.PlacedAllocs,
+			Healthy:           state.HealthyAllocs,
+			Unhealthy:         state.UnhealthyAllocs,
+			DesiredCanaries:   state.DesiredCanaries,
+			PlacedCanaries:    len(state.PlacedCanaries),
+			Promoted:          state.Promoted,
+			AutoRevert:        state.AutoRevert,
+			RequireProgressBy: state.RequireProgressBy,
+		})
+	}
+	sort.Slice(details.TaskGroups, func(i, j int) bool { return details.TaskGroups[i].Name < details.TaskGroups[j].Name })
+
+	return details
+}
+
+func buildEvaluations(evaluations []*nomad.Evaluation) []evaluationFormatProvider {
+	result := make([]evaluationFormatProvider, 0, len(evaluations))
+	for _, evaluation := range evaluations {
+		result = append(result, buildEvaluation(evaluation))
+	}
+	return result
+}
+
+func buildEvaluation(evaluation *nomad.Evaluation) evaluationFormatProvider {
+	details := evaluationFormatProvider{
+		ID:                evaluation.ID,
+		Type:              evaluation.Type,
+		Status:            evaluation.Status,
+		StatusDescription: evaluation.StatusDescription,
+		TriggeredBy:       evaluation.TriggeredBy,
+		JobID:             evaluation.JobID,
+		JobModifyIndex:    evaluation.JobModifyIndex,
+		DeploymentID:      evaluation.DeploymentID,
+		NodeID:            evaluation.NodeID,
+		BlockedEval:       evaluation.BlockedEval,
+		NextEval:          evaluation.NextEval,
+		PreviousEval:      evaluation.PreviousEval,
+		QueuedAllocations: evaluation.QueuedAllocations,
+		CreateTime:        time.Unix(0, evaluation.CreateTime),
+		FailedPlacements:  make([]trekPlacementFailure, 0),
+	}
+
+	for group, metric := range evaluation.FailedTGAllocs {
+		details.FailedPlacements = append(details.FailedPlacements, trekPlacementFailure{
+			TaskGroup:          group,
+			Failed:             metric.CoalescedFailures + 1,
+			NodesEvaluated:     metric.NodesEvaluated,
+			NodesFiltered:      metric.NodesFiltered,
+			NodesExhausted:     metric.NodesExhausted,
+			ClassFiltered:      metric.ClassFiltered,
+			ConstraintFiltered: metric.ConstraintFiltered,
+			ClassExhausted:     metric.ClassExhausted,
+			DimensionExhausted: metric.DimensionExhausted,
+			QuotaExhausted:     metric.QuotaExhausted,
+			Reasons:            placementFailureReasons(metric),
+		})
+	}
+	sort.Slice(details.FailedPlacements, func(i, j int) bool {
+		return details.FailedPlacements[i].TaskGroup < details.FailedPlacements[j].TaskGroup
+	})
+
+	return details
+}
+
+func buildJobSummary(summary *nomad.JobSummary) trekJobSummary {
+	result := trekJobSummary{}
+	if summary == nil {
+		return result
+	}
+
+	for _, taskGroup := range summary.Summary {
+		result.Queued += taskGroup.Queued
+		result.Starting += taskGroup.Starting
+		result.Running += taskGroup.Running
+		result.Complete += taskGroup.Complete
+		result.Failed += taskGroup.Failed
+		result.Lost += taskGroup.Lost
+	}
+
+	return result
+}
+
+func buildJobs(jobs []nomad.JobListStub) []trekJob {
 	result := make([]trekJob, 0)
 
 	for _, job := range jobs {
-		result = append(result, trekJob{Name: *job.Name})
+		result = append(result, trekJob{
+			ID:       job.ID,
+			Name:     job.Name,
+			Status:   job.Status,
+			Type:     job.Type,
+			Priority: job.Priority,
+			Summary:  buildJobSummary(job.JobSummary),
+		})
 	}
 
 	return result
 }
+
+func buildTaskDetails(alloc allocation, task *nomad.Task) (taskFormatProvider, error) {
+	var networks []*nomad.NetworkResource
+	if resources, ok := alloc.allocation.TaskResources[task.Name]; ok && resources != nil {
+		networks = resources.Networks
+	}
+
+	network, err := buildNetwork(networks)
+	if err != nil {
+		return taskFormatProvider{}, err
+	}
+
+	return taskFormatProvider{
+		Task:        trekTask{Name: task.Name, Driver: task.Driver, Config: task.Config},
+		Node:        trekNode{Name: alloc.node.Name, IP: alloc.IP()},
+		Network:     network,
+		Environment: buildEnv(task.Env),
+	}, nil
+}
@@ -1,32 +1,73 @@
 package main
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
-	"log"
 	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/hashicorp/nomad/api"
 	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jroimartin/gocui"
+	"gopkg.in/yaml.v3"
 )
 
 type configuration struct {
 	Environments *[]environment
+
+	// Keys rebinds the actions of the UI
+	Keys *keymap
+
+	// Theme sets the colors of the UI
+	Theme *themeConfig
 }
 
 type environment struct {
-	Name    string
-	Address string
+	Name      string
+	Address   string
+	Token     string
+	Namespace string
+	Region    string
+
+	CACert        string
+	ClientCert    string
+	ClientKey     string
+	TLSServerName string
+	TLSSkipVerify bool
+
+	// ReadOnly disables every action changing the cluster
+	ReadOnly bool
+	// RequireConfirmation asks before running any action changing the cluster
+	RequireConfirmation bool
+	// UseCommandLineToken sends the token of the command line (or
+	// $NOMAD_TOKEN) when the environment has none
+	UseCommandLineToken bool
+
+	// commandLine marks the environment built from the command line flags
+	commandLine bool
+}
+
+// Scope describes the namespace and region an environment is bound to
+func (env environment) Scope() string {
+	scope := make([]string, 0)
+	if env.Namespace != "" {
+		scope = append(scope, "ns:"+env.Namespace)
+	}
+	if env.Region != "" {
+		scope = append(scope, "region:"+env.Region)
+	}
+	return strings.Join(scope, " ")
 }
 
-func (config *configuration) addEnvironment(name string, address string) {
+func (config *configuration) addEnvironment(env environment) {
 	if config.Environments == nil {
 		config.Environments = new([]environment)
 	}
-	*config.Environments = append(*config.Environments, environment{Name: name, Address: address})
+	*config.Environments = append(*config.Environments, env)
 }
 
 type cursorPosition struct {
@@ -57,12 +98,42 @@ type trekStateType struct {
 	selectedAllocationGroup   int
 	selectedAllocationIndex   int
 	foundAllocations          []nomad.Allocation
+	allocationStatusFilter    allocationStatusFilter
 	selectedTask              int
 	foundTasks                []nomad.Task
 	client                    *nomad.Client
-	jobs                      []nomad.Job
+	directClient              *nomad.Client
+	requests                  *requestLog
+	jobs                      []nomad.JobListStub
+	currentJob                *nomad.Job
+	jobCache                  map[jobCacheKey]*nomad.Job
+	nodes                     []*nomad.NodeListStub
+	selectedNode              int
+	nodeAllocations           []*nomad.Allocation
+	selectedNodeAllocation    int
+	deployments               []*nomad.Deployment
+	selectedDeployment        int
+	evaluations               []*nomad.Evaluation
+	selectedEvaluation        int
 	nomadConnectConfiguration configuration
+	defaultEnvironment        environment
+	connectedEnvironment      environment
+	readOnly                  bool
 	activeViews               []uiHandlerWithStateType
+	watchers                  map[string]chan struct{}
+	filters                   map[string]*listFilter
+	bindings                  []binding
+	prompt                    *promptState
+	confirm                   *confirmState
+	logs                      *logViewState
+	files                     *filesViewState
+	stats                     *statsViewState
+	spec                      *specViewState
+	plan                      *planViewState
+	help                      *helpViewState
+	inspector                 *inspectorState
+	theme                     *theme
+	pendingExec               *execRequest
 	lastView                  *gocui.View
 }
 
@@ -79,97 +150,373 @@ func (alloc allocation) IP() string {
 	return alloc.node.Attributes["unique.network.ip-address"]
 }
 
-func (trekState *trekStateType) getNodeFromAllocation(alloc nomad.Allocation) api.Node {
+func (trekState *trekStateType) getNodeFromAllocation(alloc nomad.Allocation) (api.Node, error) {
 	options := &nomad.QueryOptions{}
 	nodes := trekState.client.Nodes()
 	node, _, err := nodes.Info(alloc.NodeID, options)
 
 	if err != nil {
-		log.Panicln(err)
+		return api.Node{}, apiError(err, "cannot fetch node %s", alloc.NodeID)
 	}
-	return *node
+	return *node, nil
 }
 
 func (trekState *trekStateType) CurrentAllocation() (allocation, error) {
 	index := trekState.selectedAllocationIndex
 
-	if index > len(trekState.foundAllocations)-1 {
-		return allocation{}, errors.New("allocation not found")
+	if index < 0 || index > len(trekState.foundAllocations)-1 {
+		return allocation{}, newTrekError(NotFoundError, nil, "allocation not found")
 	}
 
 	alloc := trekState.foundAllocations[index]
-	node := trekState.getNodeFromAllocation(alloc)
+	node, err := trekState.getNodeFromAllocation(alloc)
+	if err != nil {
+		return allocation{}, err
+	}
 	return allocation{allocation: alloc, node: node}, nil
 }
+
+// RefreshCurrentAllocation fetches the latest version of the selected allocation
+func (trekState *trekStateType) RefreshCurrentAllocation() error {
+	index := trekState.selectedAllocationIndex
+
+	if index < 0 || index > len(trekState.foundAllocations)-1 {
+		return newTrekError(NotFoundError, nil, "allocation not found")
+	}
+
+	id := trekState.foundAllocations[index].ID
+	alloc, _, err := trekState.client.Allocations().Info(id, &nomad.QueryOptions{})
+	if err != nil {
+		return apiError(err, "cannot fetch allocation %s", id)
+	}
+	trekState.foundAllocations[index] = *alloc
+	return nil
+}
+
+// jobCacheKey identifies a job across the clusters of a session
+type jobCacheKey struct {
+	cluster   string
+	namespace string
+	id        string
+}
+
+// LoadCurrentJob fetches the full specification of the selected job, reusing
+// the copy fetched earlier in the session unless the job has been modified
+func (trekState *trekStateType) LoadCurrentJob() error {
+	return trekState.loadCurrentJob(true)
+}
+
+// ReloadCurrentJob fetches the full specification of the selected job,
+// bypassing the session cache
+func (trekState *trekStateType) ReloadCurrentJob() error {
+	return trekState.loadCurrentJob(false)
+}
+
+func (trekState *trekStateType) loadCurrentJob(useCache bool) error {
+	if trekState.selectedJob < 0 || trekState.selectedJob > len(trekState.jobs)-1 {
+		return newTrekError(NotFoundError, nil, "job not found")
+	}
+	stub := trekState.jobs[trekState.selectedJob]
+	key := jobCacheKey{cluster: trekState.CurrentEnvironment().Name, namespace: stub.Namespace, id: stub.ID}
+
+	if trekState.jobCache == nil {
+		trekState.jobCache = make(map[jobCacheKey]*nomad.Job)
+	}
+
+	if job, ok := trekState.jobCache[key]; ok && useCache && job.JobModifyIndex != nil && *job.JobModifyIndex == stub.JobModifyIndex {
+		trekState.currentJob = job
+		return nil
+	}
+
+	options := &nomad.QueryOptions{Namespace: stub.Namespace}
+	job, _, err := trekState.client.Jobs().Info(stub.ID, options)
+	if err != nil {
+		return apiError(err, "cannot fetch job %s", stub.ID)
+	}
+	trekState.jobCache[key] = job
+	trekState.currentJob = job
+	return nil
+}
+
+// CurrentJob returns the job loaded by LoadCurrentJob
 func (trekState *trekStateType) CurrentJob() nomad.Job {
-	return trekState.jobs[trekState.selectedJob]
+	return *trekState.currentJob
 }
 func (trekState *trekStateType) CurrentTaskGroups() []*nomad.TaskGroup {
 	return trekState.CurrentJob().TaskGroups
 }
 
-func (trekState *trekStateType) CurrentTaskGroup() nomad.TaskGroup {
-	return *trekState.CurrentJob().TaskGroups[trekState.selectedAllocationGroup]
+// CurrentTaskGroup returns the selected task group.  The job may have been
+// reloaded with fewer task groups since it was selected.
+func (trekState *trekStateType) CurrentTaskGroup() (nomad.TaskGroup, error) {
+	taskGroups := trekState.CurrentTaskGroups()
+	index := trekState.selectedAllocationGroup
+	if index < 0 || index > len(taskGroups)-1 {
+		return nomad.TaskGroup{}, newTrekError(NotFoundError, nil, "task group not found")
+	}
+	return *taskGroups[index], nil
 }
+
+// Tasks lists the tasks of the selected task group, if it still exists
 func (trekState *trekStateType) Tasks() []*nomad.Task {
-	return trekState.CurrentTaskGroup().Tasks
+	taskGroup, err := trekState.CurrentTaskGroup()
+	if err != nil {
+		return nil
+	}
+	return taskGroup.Tasks
 }
-func (trekState *trekStateType) CurrentTask() *nomad.Task {
-	return trekState.Tasks()[trekState.selectedTask]
+func (trekState *trekStateType) CurrentTask() (*nomad.Task, error) {
+	tasks := trekState.Tasks()
+	index := trekState.selectedTask
+	if index < 0 || index > len(tasks)-1 {
+		return nil, newTrekError(NotFoundError, nil, "task not found")
+	}
+	return tasks[index], nil
 }
 
-func (trekState *trekStateType) CurrentAllocations() []nomad.Allocation {
-	options := &nomad.QueryOptions{}
+// allocationWorkers bounds how many allocations are fetched concurrently
+const allocationWorkers = 8
+
+// fetchAllocations retrieves the full allocations behind the given stubs,
+// preserving their order.  Allocations garbage-collected since the stubs were
+// listed are left out.
+func (trekState *trekStateType) fetchAllocations(stubs []*nomad.AllocationListStub) ([]nomad.Allocation, error) {
 	allocs := trekState.client.Allocations()
-	allocsListStub, _, _ := allocs.List(options)
 
-	trekState.foundAllocations = make([]nomad.Allocation, 0)
+	results := make([]*nomad.Allocation, len(stubs))
+	errs := make([]error, len(stubs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < allocationWorkers && worker < len(stubs); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				alloc, _, err := allocs.Info(stubs[index].ID, &nomad.QueryOptions{Namespace: stubs[index].Namespace})
+				if err != nil {
+					errs[index] = apiError(err, "cannot fetch allocation %s", stubs[index].ID)
+					continue
+				}
+				results[index] = alloc
+			}
+		}()
+	}
 
-	taskGroup := trekState.CurrentTaskGroup()
+	for index := range stubs {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
 
-	for _, stub := range allocsListStub {
-		alloc, _, err := allocs.Info(stub.ID, options)
+	fetched := make([]nomad.Allocation, 0, len(stubs))
+	for index, err := range errs {
+		if isNotFound(err) {
+			continue
+		}
 		if err != nil {
-			log.Panicln(err)
+			return nil, err
 		}
-		if alloc.TaskGroup == *taskGroup.Name {
-			if alloc.ClientStatus == "running" {
-				trekState.foundAllocations = append(trekState.foundAllocations, *alloc)
-			}
+		fetched = append(fetched, *results[index])
+	}
+	return fetched, nil
+}
+
+// allocationStatuses lists the client statuses an allocation can be in
+var allocationStatuses = []string{"pending", "running", "complete", "failed", "lost"}
+
+// allocationStatusFilter lists the client statuses of the allocations to show.
+// An empty filter matches every allocation.
+type allocationStatusFilter []string
+
+func parseAllocationStatusFilter(statuses string) allocationStatusFilter {
+	filter := make(allocationStatusFilter, 0)
+	for _, status := range strings.Split(statuses, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			filter = append(filter, status)
+		}
+	}
+	return filter
+}
+
+func (filter allocationStatusFilter) Validate() error {
+	for _, status := range filter {
+		valid := false
+		for _, known := range allocationStatuses {
+			valid = valid || status == known
+		}
+		if !valid {
+			return newTrekError(GenericError, nil, "unknown allocation status %s (expected one of %s)", status, strings.Join(allocationStatuses, ", "))
+		}
+	}
+	return nil
+}
+
+func (filter allocationStatusFilter) Matches(status string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, candidate := range filter {
+		if candidate == status {
+			return true
 		}
 	}
-	sort.SliceStable(trekState.foundAllocations, func(i, j int) bool { return trekState.foundAllocations[i].Name < trekState.foundAllocations[j].Name })
-	return trekState.foundAllocations
+	return false
+}
+
+func (filter allocationStatusFilter) String() string {
+	if len(filter) == 0 {
+		return "all"
+	}
+	return strings.Join(filter, ",")
 }
 
-func (trekState *trekStateType) Jobs() []nomad.Job {
+// CurrentAllocations lists the allocations of the selected task group whose
+// status matches the allocation status filter
+func (trekState *trekStateType) CurrentAllocations() ([]nomad.Allocation, error) {
+	job := trekState.CurrentJob()
+	taskGroup, err := trekState.CurrentTaskGroup()
+	if err != nil {
+		return nil, err
+	}
+	options := &nomad.QueryOptions{}
+	if job.Namespace != nil {
+		options.Namespace = *job.Namespace
+	}
+
+	allocsListStub, _, err := trekState.client.Jobs().Allocations(*job.ID, false, options)
+	if err != nil {
+		return nil, apiError(err, "cannot list allocations of job %s", *job.ID)
+	}
+
+	stubs := make([]*nomad.AllocationListStub, 0)
+	for _, stub := range allocsListStub {
+		if stub.JobID == *job.ID && stub.TaskGroup == *taskGroup.Name && trekState.allocationStatusFilter.Matches(stub.ClientStatus) {
+			stubs = append(stubs, stub)
+		}
+	}
+	// Sort by name, most recent allocations first
+	sort.SliceStable(stubs, func(i, j int) bool {
+		if stubs[i].Name == stubs[j].Name {
+			return stubs[i].CreateTime > stubs[j].CreateTime
+		}
+		return stubs[i].Name < stubs[j].Name
+	})
+
+	trekState.foundAllocations, err = trekState.fetchAllocations(stubs)
+	if err != nil {
+		trekState.foundAllocations = make([]nomad.Allocation, 0)
+		return nil, err
+	}
+	return trekState.foundAllocations, nil
+}
+
+// Jobs lists the jobs of the cluster.  Only list stubs are fetched, see
+// LoadCurrentJob to get the full specification of the selected job.
+func (trekState *trekStateType) Jobs() ([]nomad.JobListStub, error) {
 	options := &nomad.QueryOptions{}
 	jobListStubs, _, err := trekState.client.Jobs().List(options)
 
 	if err != nil {
-		log.Panicln(err)
+		return nil, apiError(err, "cannot list jobs")
 	}
 
-	trekState.jobs = make([]nomad.Job, 0)
+	trekState.jobs = make([]nomad.JobListStub, 0)
 	for _, job := range jobListStubs {
-		fullJob, _, _ := trekState.client.Jobs().Info(job.ID, options)
-		trekState.jobs = append(trekState.jobs, *fullJob)
+		trekState.jobs = append(trekState.jobs, *job)
 	}
-	return trekState.jobs
+	return trekState.jobs, nil
 }
 
-func (trekState *trekStateType) Connect() error {
+func coalesce(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// clientConfig describes how to reach an environment.  Settings missing from
+// the environment fall back on the command line flags, then on the standard
+// NOMAD_* environment variables, except for the ACL token: the one of the
+// command line (or $NOMAD_TOKEN) is only sent to the environment built from
+// the command line and to those setting UseCommandLineToken.
+func (trekState *trekStateType) clientConfig(env environment) *nomad.Config {
+	defaults := trekState.defaultEnvironment
+
 	config := nomad.DefaultConfig()
-	config.Address = trekState.CurrentEnvironment().Address
+	config.Address = env.Address
+	token := env.Token
+	if token == "" && (env.commandLine || env.UseCommandLineToken) {
+		// DefaultConfig reads $NOMAD_TOKEN
+		token = coalesce(defaults.Token, config.SecretID)
+	}
+	config.SecretID = token
+	if namespace := coalesce(env.Namespace, defaults.Namespace); namespace != "" {
+		config.Namespace = namespace
+	}
+	if region := coalesce(env.Region, defaults.Region); region != "" {
+		config.Region = region
+	}
+	if caCert := coalesce(env.CACert, defaults.CACert); caCert != "" {
+		config.TLSConfig.CACert = caCert
+	}
+	if clientCert := coalesce(env.ClientCert, defaults.ClientCert); clientCert != "" {
+		config.TLSConfig.ClientCert = clientCert
+	}
+	if clientKey := coalesce(env.ClientKey, defaults.ClientKey); clientKey != "" {
+		config.TLSConfig.ClientKey = clientKey
+	}
+	if serverName := coalesce(env.TLSServerName, defaults.TLSServerName); serverName != "" {
+		config.TLSConfig.TLSServerName = serverName
+	}
+	if env.TLSSkipVerify || defaults.TLSSkipVerify {
+		config.TLSConfig.Insecure = true
+	}
+	return config
+}
+
+// Connect builds a client for the current environment
+func (trekState *trekStateType) Connect() error {
+	env := trekState.CurrentEnvironment()
+	config := trekState.clientConfig(env)
+
 	var err error
 	trekState.client, err = nomad.NewClient(config)
 
 	if err != nil {
-		return err
+		return newTrekError(ConnectionError, err, "cannot connect to %s", config.Address)
 	}
+	trekState.directClient = trekState.client
+
+	// the UI records its requests for the inspector
+	if trekState.requests != nil {
+		recording := *config
+		if recording.HttpClient, err = recordingHTTPClient(config.TLSConfig, trekState.requests); err != nil {
+			return newTrekError(ConnectionError, err, "cannot connect to %s", config.Address)
+		}
+		if trekState.client, err = nomad.NewClient(&recording); err != nil {
+			return newTrekError(ConnectionError, err, "cannot connect to %s", config.Address)
+		}
+	}
+	trekState.connectedEnvironment = env
 	return nil
 }
 
+// ReadOnly tells whether actions changing the connected cluster are disabled,
+// either globally or for its environment
+func (trekState *trekStateType) ReadOnly() bool {
+	return trekState.readOnly || trekState.connectedEnvironment.ReadOnly
+}
+
+func (trekState *trekStateType) readOnlyError() error {
+	if trekState.readOnly {
+		return newTrekError(ReadOnlyError, nil, "trek runs in read-only mode")
+	}
+	return newTrekError(ReadOnlyError, nil, "environment %s is read-only", trekState.connectedEnvironment.Name)
+}
+
 type boundsType struct {
 	startX int
 	startY int
@@ -177,91 +524,275 @@ type boundsType struct {
 	endY   int
 }
 
-// binding is some binding
+// binding is some binding.  The action names what the handler does, so that
+// the keymap of .trek.rc can bind it to other keys.
 type binding struct {
 	panelName string
-	key       gocui.Key
+	key       interface{}
+	action    string
 	handler   uiHandlerWithStateType
 }
 
 type trekNode struct {
-	Name string
-	IP   string
+	Name string `json:"name" yaml:"name"`
+	IP   string `json:"ip" yaml:"ip"`
 }
 
 type taskFormatProvider struct {
-	Task        trekTask
-	Node        trekNode
-	Network     trekCommandNetwork
-	Environment trekCommandEnvironment
+	Task        trekTask               `json:"task" yaml:"task"`
+	Node        trekNode               `json:"node" yaml:"node"`
+	Network     trekCommandNetwork     `json:"network" yaml:"network"`
+	Environment trekCommandEnvironment `json:"environment" yaml:"environment"`
 }
 
 type trekCommandNetwork struct {
-	DynamicPorts  []trekCommandPort
-	ReservedPorts []trekCommandPort
+	DynamicPorts  []trekCommandPort `json:"dynamic_ports" yaml:"dynamic_ports"`
+	ReservedPorts []trekCommandPort `json:"reserved_ports" yaml:"reserved_ports"`
 }
 
 type trekCommandPort struct {
-	Name   string
-	Number int
+	Name   string `json:"name" yaml:"name"`
+	Number int    `json:"number" yaml:"number"`
 }
 
 type trekCommandEnvironment map[string]trekCommandEnvironmentVariable
 
 type trekCommandEnvironmentVariable struct {
-	Value string
+	Value string `json:"value" yaml:"value"`
 }
 
-func trekPrintDetails(w io.Writer, format string, data interface{}) {
+func trekPrintDetails(w io.Writer, format string, data interface{}) error {
 
 	tmpl, err := template.
 		New("output").
 		Funcs(template.FuncMap{
 			"Debug":    func(structure interface{}) string { return fmt.Sprintf("DEBUG: %+v\n", structure) },
 			"DebugAll": func() string { return fmt.Sprintf("DEBUG ALL: %+v\n", data) },
+			"Bytes":    formatBytes,
 		}).
 		Parse(format)
 
 	if err != nil {
-		panic(err)
+		return newTrekError(TemplateError, err, "invalid display format")
 	}
 	err = tmpl.Execute(w, data)
 	if err != nil {
-		panic(err)
+		return newTrekError(TemplateError, err, "cannot render display format")
+	}
+	return nil
+}
+
+// trekPrintOutput prints data using the requested output format, falling back
+// on Go templates (and the given default template) for text output
+func trekPrintOutput(w io.Writer, output OutputFormat, format string, data interface{}) error {
+	switch output {
+	case JSONOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case YAMLOutput:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(data); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case TextOutput, "":
+		return trekPrintDetails(w, format, data)
+	default:
+		return fmt.Errorf("unknown output format %q", output)
 	}
 }
 
 type allocationFormatProvider struct {
-	IP    string
-	Tasks []trekTask
+	IP    string     `json:"ip" yaml:"ip"`
+	Tasks []trekTask `json:"tasks" yaml:"tasks"`
 }
 
 type trekTask struct {
-	Name   string
-	Driver string
-	Config map[string]interface{}
+	Name   string                 `json:"name" yaml:"name"`
+	Driver string                 `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Config map[string]interface{} `json:"config,omitempty" yaml:"config,omitempty"`
 }
 
 type taskGroupFormatProvider struct {
-	Allocations []trekAllocation
+	Allocations []trekAllocation `json:"allocations" yaml:"allocations"`
 }
 
 type trekAllocation struct {
-	Name string
+	ID            string    `json:"id" yaml:"id"`
+	Name          string    `json:"name" yaml:"name"`
+	ClientStatus  string    `json:"client_status" yaml:"client_status"`
+	DesiredStatus string    `json:"desired_status" yaml:"desired_status"`
+	CreateTime    time.Time `json:"create_time" yaml:"create_time"`
 }
 
 type jobFormatProvider struct {
-	TaskGroups []trekTaskGroup
+	TaskGroups []trekTaskGroup `json:"task_groups" yaml:"task_groups"`
 }
 
 type trekTaskGroup struct {
-	Name string
+	Name string `json:"name" yaml:"name"`
+}
+
+type deploymentFormatProvider struct {
+	ID                string                `json:"id" yaml:"id"`
+	JobID             string                `json:"job_id" yaml:"job_id"`
+	JobVersion        uint64                `json:"job_version" yaml:"job_version"`
+	Status            string                `json:"status" yaml:"status"`
+	StatusDescription string                `json:"status_description" yaml:"status_description"`
+	TaskGroups        []trekDeploymentGroup `json:"task_groups" yaml:"task_groups"`
+}
+
+type trekDeploymentGroup struct {
+	Name              string    `json:"name" yaml:"name"`
+	Desired           int       `json:"desired" yaml:"desired"`
+	Placed            int       `json:"placed" yaml:"placed"`
+	Healthy           int       `json:"healthy" yaml:"healthy"`
+	Unhealthy         int       `json:"unhealthy" yaml:"unhealthy"`
+	DesiredCanaries   int       `json:"desired_canaries" yaml:"desired_canaries"`
+	PlacedCanaries    int       `json:"placed_canaries" yaml:"placed_canaries"`
+	Promoted          bool      `json:"promoted" yaml:"promoted"`
+	AutoRevert        bool      `json:"auto_revert" yaml:"auto_revert"`
+	RequireProgressBy time.Time `json:"require_progress_by" yaml:"require_progress_by"`
+}
+
+type evaluationsFormatProvider struct {
+	Evaluations []evaluationFormatProvider `json:"evaluations" yaml:"evaluations"`
+}
+
+type evaluationFormatProvider struct {
+	ID                string                 `json:"id" yaml:"id"`
+	Type              string                 `json:"type" yaml:"type"`
+	Status            string                 `json:"status" yaml:"status"`
+	StatusDescription string                 `json:"status_description" yaml:"status_description"`
+	TriggeredBy       string                 `json:"triggered_by" yaml:"triggered_by"`
+	JobID             string                 `json:"job_id" yaml:"job_id"`
+	JobModifyIndex    uint64                 `json:"job_modify_index" yaml:"job_modify_index"`
+	DeploymentID      string                 `json:"deployment_id,omitempty" yaml:"deployment_id,omitempty"`
+	NodeID            string                 `json:"node_id,omitempty" yaml:"node_id,omitempty"`
+	BlockedEval       string                 `json:"blocked_eval,omitempty" yaml:"blocked_eval,omitempty"`
+	NextEval          string                 `json:"next_eval,omitempty" yaml:"next_eval,omitempty"`
+	PreviousEval      string                 `json:"previous_eval,omitempty" yaml:"previous_eval,omitempty"`
+	QueuedAllocations map[string]int         `json:"queued_allocations,omitempty" yaml:"queued_allocations,omitempty"`
+	CreateTime        time.Time              `json:"create_time" yaml:"create_time"`
+	FailedPlacements  []trekPlacementFailure `json:"failed_placements" yaml:"failed_placements"`
+}
+
+type trekPlacementFailure struct {
+	TaskGroup          string         `json:"task_group" yaml:"task_group"`
+	Failed             int            `json:"failed" yaml:"failed"`
+	NodesEvaluated     int            `json:"nodes_evaluated" yaml:"nodes_evaluated"`
+	NodesFiltered      int            `json:"nodes_filtered" yaml:"nodes_filtered"`
+	NodesExhausted     int            `json:"nodes_exhausted" yaml:"nodes_exhausted"`
+	ClassFiltered      map[string]int `json:"class_filtered,omitempty" yaml:"class_filtered,omitempty"`
+	ConstraintFiltered map[string]int `json:"constraint_filtered,omitempty" yaml:"constraint_filtered,omitempty"`
+	ClassExhausted     map[string]int `json:"class_exhausted,omitempty" yaml:"class_exhausted,omitempty"`
+	DimensionExhausted map[string]int `json:"dimension_exhausted,omitempty" yaml:"dimension_exhausted,omitempty"`
+	QuotaExhausted     []string       `json:"quota_exhausted,omitempty" yaml:"quota_exhausted,omitempty"`
+	Reasons            []string       `json:"reasons" yaml:"reasons"`
+}
+
+type nodesFormatProvider struct {
+	Nodes []trekNodeStub `json:"nodes" yaml:"nodes"`
+}
+
+type trekNodeStub struct {
+	ID                    string `json:"id" yaml:"id"`
+	Name                  string `json:"name" yaml:"name"`
+	Address               string `json:"address" yaml:"address"`
+	Datacenter            string `json:"datacenter" yaml:"datacenter"`
+	NodeClass             string `json:"node_class" yaml:"node_class"`
+	Version               string `json:"version" yaml:"version"`
+	Status                string `json:"status" yaml:"status"`
+	SchedulingEligibility string `json:"scheduling_eligibility" yaml:"scheduling_eligibility"`
+	Drain                 bool   `json:"drain" yaml:"drain"`
+}
+
+type nodeFormatProvider struct {
+	ID                    string               `json:"id" yaml:"id"`
+	Name                  string               `json:"name" yaml:"name"`
+	Address               string               `json:"address" yaml:"address"`
+	Datacenter            string               `json:"datacenter" yaml:"datacenter"`
+	NodeClass             string               `json:"node_class" yaml:"node_class"`
+	Status                string               `json:"status" yaml:"status"`
+	SchedulingEligibility string               `json:"scheduling_eligibility" yaml:"scheduling_eligibility"`
+	Drain                 bool                 `json:"drain" yaml:"drain"`
+	Resources             trekNodeResources    `json:"resources" yaml:"resources"`
+	Drivers               []trekNodeDriver     `json:"drivers" yaml:"drivers"`
+	Attributes            map[string]string    `json:"attributes" yaml:"attributes"`
+	Meta                  map[string]string    `json:"meta" yaml:"meta"`
+	Allocations           []trekNodeAllocation `json:"allocations" yaml:"allocations"`
+}
+
+type trekNodeResources struct {
+	CPU      int64 `json:"cpu" yaml:"cpu"`
+	MemoryMB int64 `json:"memory_mb" yaml:"memory_mb"`
+	DiskMB   int64 `json:"disk_mb" yaml:"disk_mb"`
+}
+
+type trekNodeDriver struct {
+	Name     string `json:"name" yaml:"name"`
+	Detected bool   `json:"detected" yaml:"detected"`
+	Healthy  bool   `json:"healthy" yaml:"healthy"`
+}
+
+type trekNodeAllocation struct {
+	ID            string `json:"id" yaml:"id"`
+	Name          string `json:"name" yaml:"name"`
+	JobID         string `json:"job_id" yaml:"job_id"`
+	TaskGroup     string `json:"task_group" yaml:"task_group"`
+	ClientStatus  string `json:"client_status" yaml:"client_status"`
+	DesiredStatus string `json:"desired_status" yaml:"desired_status"`
+}
+
+type statsFormatProvider struct {
+	Timestamp time.Time       `json:"timestamp" yaml:"timestamp"`
+	Tasks     []trekTaskStats `json:"tasks" yaml:"tasks"`
+}
+
+type trekTaskStats struct {
+	Name             string  `json:"name" yaml:"name"`
+	CPUPercent       float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	CPUTotalTicks    float64 `json:"cpu_total_ticks" yaml:"cpu_total_ticks"`
+	ThrottledPeriods uint64  `json:"throttled_periods" yaml:"throttled_periods"`
+	ThrottledTime    uint64  `json:"throttled_time" yaml:"throttled_time"`
+	MemoryRSS        uint64  `json:"memory_rss" yaml:"memory_rss"`
+	MemoryCache      uint64  `json:"memory_cache" yaml:"memory_cache"`
+	MemorySwap       uint64  `json:"memory_swap" yaml:"memory_swap"`
+}
+
+type filesFormatProvider struct {
+	Path  string     `json:"path" yaml:"path"`
+	Files []trekFile `json:"files" yaml:"files"`
+}
+
+type trekFile struct {
+	Name     string    `json:"name" yaml:"name"`
+	IsDir    bool      `json:"is_dir" yaml:"is_dir"`
+	Size     int64     `json:"size" yaml:"size"`
+	FileMode string    `json:"file_mode" yaml:"file_mode"`
+	ModTime  time.Time `json:"mod_time" yaml:"mod_time"`
 }
 
 type jobsFormatProvider struct {
-	Jobs []trekJob
+	Jobs []trekJob `json:"jobs" yaml:"jobs"`
 }
 
 type trekJob struct {
-	Name string
+	ID       string         `json:"id" yaml:"id"`
+	Name     string         `json:"name" yaml:"name"`
+	Status   string         `json:"status" yaml:"status"`
+	Type     string         `json:"type" yaml:"type"`
+	Priority int            `json:"priority" yaml:"priority"`
+	Summary  trekJobSummary `json:"summary" yaml:"summary"`
+}
+
+type trekJobSummary struct {
+	Queued   int `json:"queued" yaml:"queued"`
+	Starting int `json:"starting" yaml:"starting"`
+	Running  int `json:"running" yaml:"running"`
+	Complete int `json:"complete" yaml:"complete"`
+	Failed   int `json:"failed" yaml:"failed"`
+	Lost     int `json:"lost" yaml:"lost"`
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	nomad "github.com/hashicorp/nomad/api"
+)
+
+// allocationServer answers the allocation lookups of the nomad API: known
+// allocations are returned, "gone" ones are missing, and the others fail
+func allocationServer(t *testing.T, gone map[string]bool) (*trekStateType, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/v1/allocation/")
+		switch {
+		case gone[id]:
+			http.Error(w, "alloc not found", http.StatusNotFound)
+		case strings.HasPrefix(id, "broken"):
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(nomad.Allocation{ID: id, Name: "web[" + id + "]"})
+		}
+	}))
+	client, err := nomad.NewClient(&nomad.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("cannot create the client: %v", err)
+	}
+	return &trekStateType{client: client}, server
+}
+
+func stubsOf(ids ...string) []*nomad.AllocationListStub {
+	stubs := make([]*nomad.AllocationListStub, 0, len(ids))
+	for _, id := range ids {
+		stubs = append(stubs, &nomad.AllocationListStub{ID: id})
+	}
+	return stubs
+}
+
+func TestFetchAllocationsSkipsCollectedAllocations(t *testing.T) {
+	trekState, server := allocationServer(t, map[string]bool{"b": true, "d": true})
+	defer server.Close()
+
+	allocs, err := trekState.fetchAllocations(stubsOf("a", "b", "c", "d", "e"))
+	if err != nil {
+		t.Fatalf("fetchAllocations: %v", err)
+	}
+	ids := make([]string, 0, len(allocs))
+	for _, alloc := range allocs {
+		ids = append(ids, alloc.ID)
+	}
+	if strings.Join(ids, ",") != "a,c,e" {
+		t.Errorf("fetched %v, expected a, c and e in order", ids)
+	}
+}
+
+func TestFetchAllocationsFailsOnErrors(t *testing.T) {
+	trekState, server := allocationServer(t, map[string]bool{"b": true})
+	defer server.Close()
+
+	_, err := trekState.fetchAllocations(stubsOf("a", "b", "broken-c"))
+	if err == nil {
+		t.Fatalf("fetchAllocations: expected an error")
+	}
+	if code := exitCode(err); code != ConnectionError.ExitCode() {
+		t.Errorf("exit code %d, expected %d: %v", code, ConnectionError.ExitCode(), err)
+	}
+}
+
+func TestClientConfigToken(t *testing.T) {
+	os.Setenv("NOMAD_TOKEN", "env-token")
+	defer os.Unsetenv("NOMAD_TOKEN")
+
+	tests := []struct {
+		name     string
+		flag     string
+		env      environment
+		expected string
+	}{
+		{"own token", "flag-token", environment{Token: "own-token"}, "own-token"},
+		{"no token", "flag-token", environment{}, ""},
+		{"opting in", "flag-token", environment{UseCommandLineToken: true}, "flag-token"},
+		{"opting in without flag", "", environment{UseCommandLineToken: true}, "env-token"},
+		{"command line", "flag-token", environment{commandLine: true}, "flag-token"},
+		{"command line without flag", "", environment{commandLine: true}, "env-token"},
+		{"own token on the command line", "flag-token", environment{Token: "own-token", commandLine: true}, "own-token"},
+	}
+	for _, test := range tests {
+		trekState := &trekStateType{defaultEnvironment: trekOptions{nomadToken: test.flag}.defaultEnvironment()}
+		if token := trekState.clientConfig(test.env).SecretID; token != test.expected {
+			t.Errorf("%s: token %q, expected %q", test.name, token, test.expected)
+		}
+	}
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"os"
+	"strings"
+	"time"
 
+	nomad "github.com/hashicorp/nomad/api"
 	"github.com/jroimartin/gocui"
 )
 
@@ -15,49 +18,103 @@ func stateify(handler uiHandlerWithStateType, trekState *trekStateType) uiHandle
 	}
 }
 
+// selectLine moves the cursor of a list panel to a line of the whole list,
+// scrolling the panel when needed, and selects the item shown there.  Every
+// move goes through it, so that the selection never depends on the scrolling.
+func selectLine(v *gocui.View, trekState *trekStateType, handler cursorCallback, line int) {
+	scrollTo(v, line)
+	handler(trekState, cursorPosition{x: 0, y: line})
+}
+
+// cursorDown moves the cursor of a list panel to the next line, scrolling the
+// panel when needed.  The handler is given the line in the whole list, not in
+// the visible part of the panel.
 func cursorDown(handler cursorCallback, numElementsComputer numElementsComputerCallback) uiHandlerWithStateType {
 	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
-		if v != nil {
-			cx, cy := v.Cursor()
+		if v == nil {
+			return nil
+		}
 
-			if cy >= numElementsComputer(trekState)-1 {
-				return nil
-			}
+		_, oy := v.Origin()
+		_, cy := v.Cursor()
+		line := oy + cy + 1
+		if line > numElementsComputer(trekState)-1 {
+			return nil
+		}
+		selectLine(v, trekState, handler, line)
+		return nil
+	}
+}
 
-			if err := v.SetCursor(cx, cy+1); err != nil {
-				ox, oy := v.Origin()
-				if err := v.SetOrigin(ox, oy+1); err != nil {
-					return err
-				}
-			} else {
-				handler(trekState, cursorPosition{x: cx, y: cy + 1})
-			}
+// cursorUp moves the cursor of a list panel to the previous line, scrolling
+// the panel back when needed
+func cursorUp(handler cursorCallback) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		if v == nil {
+			return nil
+		}
 
+		_, oy := v.Origin()
+		_, cy := v.Cursor()
+		line := oy + cy - 1
+		if line < 0 {
+			return nil
 		}
+		selectLine(v, trekState, handler, line)
 		return nil
 	}
 }
 
-func cursorUp(handler cursorCallback) uiHandlerWithStateType {
+const (
+	// listPage is how many lines PgUp and PgDn move through a list panel
+	listPage = 20
+
+	listTop    = math.MinInt32
+	listBottom = math.MaxInt32
+)
+
+// countLines counts the lines of a panel, ignoring the trailing empty ones
+func countLines(v *gocui.View) int {
+	lines := v.BufferLines()
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return len(lines)
+}
+
+// moveInList moves the cursor of a list panel by the given number of lines,
+// stopping on its first and last lines
+func moveInList(handler cursorCallback, lines int) uiHandlerWithStateType {
 	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
-		if v != nil {
-			ox, oy := v.Origin()
-			cx, cy := v.Cursor()
-			if cy <= 0 {
-				return nil
-			}
-			if err := v.SetCursor(cx, cy-1); err != nil && oy > 0 {
-				if err := v.SetOrigin(ox, oy-1); err != nil {
-					return err
-				}
-			} else {
-				handler(trekState, cursorPosition{x: cx, y: cy - 1})
-			}
+		if v == nil {
+			return nil
+		}
+		count := countLines(v)
+		if count == 0 {
+			return nil
+		}
+
+		_, oy := v.Origin()
+		_, cy := v.Cursor()
+		line := oy + cy + lines
+		if line < 0 {
+			line = 0
+		}
+		if line > count-1 {
+			line = count - 1
 		}
+		selectLine(v, trekState, handler, line)
 		return nil
 	}
 }
 
+// scrollPanel scrolls a panel by the given number of lines
+func scrollPanel(lines int) uiHandlerWithStateType {
+	return func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+		return scrollView(v, lines)
+	}
+}
+
 func confirmTaskSelection(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 	var l string
 	var err error
@@ -107,12 +164,13 @@ func createView(g *gocui.Gui, view trekView, trekState *trekStateType) error {
 			return err
 		}
 		v.Title = view.name
-		view.handler(v, trekState)
+		if err := view.handler(v, trekState); err != nil {
+			return err
+		}
 	}
 
 	if view.foregroundAfterCreation {
 		if _, err := g.SetCurrentView(view.name); err != nil {
-			log.Panicln(err)
 			return err
 		}
 	}
@@ -143,8 +201,23 @@ func selectCluster(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error
 		g.DeleteView("Jobs")
 	}
 
+	if err := trekState.Connect(); err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+
 	trekState.trackView(selectCluster)
 
+	client := trekState.client
+	trekState.startWatch(g, panelWatch{
+		viewName: "Jobs",
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			_, meta, err := client.Jobs().List(options)
+			return meta, err
+		},
+		render:    renderJobs,
+		selection: setSelectedJob,
+	})
+
 	return createView(g,
 		trekView{
 			name:                    "Jobs",
@@ -152,34 +225,66 @@ func selectCluster(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error
 			panelNum:                1,
 			panelsTotal:             5,
 			margin:                  0,
-			handler: func(view *gocui.View, trekState *trekStateType) error {
-				view.Highlight = true
-				view.SelBgColor = gocui.ColorGreen
-				view.SelFgColor = gocui.ColorBlack
-				view.Editable = false
-				view.Wrap = false
-
-				if err := trekState.Connect(); err != nil {
-					log.Panicln(err)
-					return nil
-				}
+			handler:                 renderJobs,
+		},
+		trekState,
+	)
+}
 
-				for _, job := range trekState.Jobs() {
-					fmt.Fprintf(view, "%s (%s)\n", *(job.ID), *(job.Status))
-				}
+func renderJobs(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	jobs, err := trekState.Jobs()
+	if err != nil {
+		fmt.Fprintln(view, err)
+		return nil
+	}
 
+	lines := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		lines = append(lines, fmt.Sprintf("%s (%s)", job.ID, trekState.theme.statusText(job.Status)))
+	}
+	renderList(view, trekState, "Jobs", lines)
+
+	return nil
+}
+
+// watchCurrentJob redraws a panel whenever the selected job is modified
+func watchCurrentJob(g *gocui.Gui, trekState *trekStateType, viewName string, render viewHandlerCallback, selection cursorCallback) {
+	client := trekState.client
+	job := trekState.CurrentJob()
+	trekState.startWatch(g, panelWatch{
+		viewName: viewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			if job.Namespace != nil {
+				options.Namespace = *job.Namespace
+			}
+			_, meta, err := client.Jobs().Info(*job.ID, options)
+			return meta, err
+		},
+		render: func(view *gocui.View, trekState *trekStateType) error {
+			if err := trekState.ReloadCurrentJob(); err != nil {
+				fmt.Fprintln(view, err)
 				return nil
-			},
+			}
+			return render(view, trekState)
 		},
-		trekState,
-	)
+		selection: selection,
+	})
 }
 
 func selectJob(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
-	if len(trekState.Jobs()) < 1 {
+	if len(trekState.jobs) < 1 {
 		return nil
 	}
 
+	if err := trekState.LoadCurrentJob(); err != nil {
+		return openPopup(g, v, trekState, err.Error())
+	}
+
 	viewName := "Task Groups"
 	_, err := g.View(viewName)
 
@@ -189,6 +294,7 @@ func selectJob(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 	}
 
 	trekState.trackView(selectJob)
+	watchCurrentJob(g, trekState, viewName, renderTaskGroups, setSelectedTaskGroup)
 
 	return createView(g,
 		trekView{
@@ -197,24 +303,27 @@ func selectJob(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 			panelNum:                2,
 			panelsTotal:             5,
 			margin:                  0,
-			handler: func(view *gocui.View, trekState *trekStateType) error {
-				view.Highlight = true
-				view.SelBgColor = gocui.ColorGreen
-				view.SelFgColor = gocui.ColorBlack
-				view.Editable = false
-				view.Wrap = false
-
-				for _, taskGroup := range trekState.CurrentTaskGroups() {
-					fmt.Fprintf(view, "%s (%d)\n", *(taskGroup.Name), *(taskGroup.Count))
-				}
-
-				return nil
-			},
+			handler:                 renderTaskGroups,
 		},
 		trekState,
 	)
 }
 
+func renderTaskGroups(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	lines := make([]string, 0)
+	for _, taskGroup := range trekState.CurrentTaskGroups() {
+		lines = append(lines, fmt.Sprintf("%s (%d)", *(taskGroup.Name), *(taskGroup.Count)))
+	}
+	renderList(view, trekState, "Task Groups", lines)
+
+	return nil
+}
+
 func selectTaskGroup(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 	viewName := "Allocations"
 	_, err := g.View(viewName)
@@ -226,6 +335,21 @@ func selectTaskGroup(g *gocui.Gui, v *gocui.View, trekState *trekStateType) erro
 
 	trekState.trackView(selectTaskGroup)
 
+	client := trekState.client
+	job := trekState.CurrentJob()
+	trekState.startWatch(g, panelWatch{
+		viewName: viewName,
+		query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+			if job.Namespace != nil {
+				options.Namespace = *job.Namespace
+			}
+			_, meta, err := client.Jobs().Allocations(*job.ID, false, options)
+			return meta, err
+		},
+		render:    renderAllocations,
+		selection: setSelectedAllocation,
+	})
+
 	return createView(g,
 		trekView{
 			name:                    viewName,
@@ -233,24 +357,83 @@ func selectTaskGroup(g *gocui.Gui, v *gocui.View, trekState *trekStateType) erro
 			panelNum:                3,
 			panelsTotal:             5,
 			margin:                  0,
-			handler: func(view *gocui.View, trekState *trekStateType) error {
-				view.Highlight = true
-				view.SelBgColor = gocui.ColorGreen
-				view.SelFgColor = gocui.ColorBlack
-				view.Editable = false
-				view.Wrap = false
-
-				for _, all := range trekState.CurrentAllocations() {
-					fmt.Fprintf(view, "%s\n", all.Name)
-				}
-
-				return nil
-			},
+			handler:                 renderAllocations,
 		},
 		trekState,
 	)
 }
 
+func renderAllocations(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	allocations, err := trekState.CurrentAllocations()
+	if err != nil {
+		fmt.Fprintln(view, err)
+		return nil
+	}
+
+	lines := make([]string, 0, len(allocations))
+	for _, all := range allocations {
+		lines = append(lines, fmt.Sprintf("%s %s (%s/%s, %s)",
+			shortID(all.ID), all.Name, trekState.theme.statusText(all.ClientStatus), all.DesiredStatus, formatAge(time.Unix(0, all.CreateTime))))
+	}
+	renderList(view, trekState, fmt.Sprintf("Allocations [%s]", trekState.allocationStatusFilter), lines)
+
+	return nil
+}
+
+// allocationStatusFilters lists the filters the Allocations panel cycles through
+var allocationStatusFilters = []allocationStatusFilter{
+	{"running"},
+	nil,
+	{"pending"},
+	{"failed"},
+	{"lost"},
+	{"complete"},
+}
+
+func cycleAllocationStatusFilter(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	next := allocationStatusFilters[0]
+	for index, filter := range allocationStatusFilters {
+		if filter.String() == trekState.allocationStatusFilter.String() {
+			next = allocationStatusFilters[(index+1)%len(allocationStatusFilters)]
+		}
+	}
+	trekState.allocationStatusFilter = next
+
+	v.SetOrigin(0, 0)
+	v.SetCursor(0, 0)
+	v.Clear()
+	err := renderAllocations(v, trekState)
+	setSelectedAllocation(trekState, cursorPosition{x: 0, y: 0})
+	return err
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// formatAge describes how long ago something happened, using its largest unit
+func formatAge(since time.Time) string {
+	age := time.Since(since)
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	case age >= time.Minute:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	default:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	}
+}
+
 func selectAllocation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 	viewName := "Tasks"
 	_, err := g.View(viewName)
@@ -261,6 +444,7 @@ func selectAllocation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) err
 	}
 
 	trekState.trackView(selectAllocation)
+	watchCurrentJob(g, trekState, viewName, renderTasks, setSelectedTask)
 
 	return createView(g,
 		trekView{
@@ -269,24 +453,45 @@ func selectAllocation(g *gocui.Gui, v *gocui.View, trekState *trekStateType) err
 			panelNum:                4,
 			panelsTotal:             5,
 			margin:                  0,
-			handler: func(view *gocui.View, trekState *trekStateType) error {
-				view.Highlight = true
-				view.SelBgColor = gocui.ColorGreen
-				view.SelFgColor = gocui.ColorBlack
-				view.Editable = false
-				view.Wrap = false
-
-				for _, task := range trekState.Tasks() {
-					fmt.Fprintf(view, "%s\n", task.Name)
-				}
-
-				return nil
-			},
+			handler:                 renderTasks,
 		},
 		trekState,
 	)
 }
 
+func renderTasks(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	if trekState.selectedAllocationGroup > len(trekState.CurrentTaskGroups())-1 {
+		fmt.Fprintln(view, "task group not found")
+		return nil
+	}
+
+	// the Tasks panel opens from an allocation, whose task states are known
+	var states map[string]*nomad.TaskState
+	if index := trekState.selectedAllocationIndex; index >= 0 && index < len(trekState.foundAllocations) {
+		states = trekState.foundAllocations[index].TaskStates
+	}
+
+	lines := make([]string, 0)
+	for _, task := range trekState.Tasks() {
+		line := task.Name
+		if state, ok := states[task.Name]; ok && state != nil {
+			line = fmt.Sprintf("%s (%s)", line, trekState.theme.statusText(state.State))
+			if state.Failed {
+				line += " " + trekState.theme.paint("failed", "failed")
+			}
+		}
+		lines = append(lines, line)
+	}
+	renderList(view, trekState, "Tasks", lines)
+
+	return nil
+}
+
 func selectTask(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 	viewName := "Task"
 	_, err := g.View(viewName)
@@ -298,6 +503,25 @@ func selectTask(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 
 	trekState.trackView(selectTask)
 
+	if alloc, err := trekState.CurrentAllocation(); err == nil {
+		client := trekState.client
+		allocID := alloc.allocation.ID
+		trekState.startWatch(g, panelWatch{
+			viewName: viewName,
+			query: func(options *nomad.QueryOptions) (*nomad.QueryMeta, error) {
+				_, meta, err := client.Allocations().Info(allocID, options)
+				return meta, err
+			},
+			render: func(view *gocui.View, trekState *trekStateType) error {
+				if err := trekState.RefreshCurrentAllocation(); err != nil {
+					fmt.Fprintln(view, err)
+					return nil
+				}
+				return renderTask(view, trekState)
+			},
+		})
+	}
+
 	return createView(g,
 		trekView{
 			name:                    viewName,
@@ -305,55 +529,68 @@ func selectTask(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
 			panelNum:                0,
 			panelsTotal:             1,
 			margin:                  10,
-			handler: func(view *gocui.View, trekState *trekStateType) error {
-				view.SelBgColor = gocui.ColorGreen
-				view.SelFgColor = gocui.ColorBlack
-				view.Editable = false
-				view.Wrap = false
-
-				alloc, err := trekState.CurrentAllocation()
-
-				if err == nil {
-					task := trekState.CurrentTask()
-
-					provider := taskFormatProvider{
-						Task:        trekTask{Name: task.Name, Driver: task.Driver, Config: task.Config},
-						Node:        trekNode{Name: alloc.node.Name, IP: alloc.IP()},
-						Network:     buildNetwork(alloc.allocation.TaskResources[task.Name].Networks),
-						Environment: buildEnv(task.Env),
-					}
-					trekPrintDetails(view, taskDetailsFormat, provider)
-				}
-				// if(trekState.debugModeEnabled) {
-				// val := reflect.Indirect(reflect.ValueOf(task))
-				// valType := val.Type()
-
-				// for i := 0; i < val.NumField(); i++ {
-				// 	field := valType.Field(i)
-				// 	value := val.FieldByName(field.Name).Interface()
-				// 	name := field.Name
-
-				// 	fmt.Fprintf(view, "%s: %+v\n", name, value)
-				// }
-				// }
-
-				return nil
-			},
+			handler:                 renderTask,
 		},
 		trekState,
 	)
 }
 
-func garbageCollect(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
-	var msg string
-	err := trekState.client.System().GarbageCollect()
+func renderTask(view *gocui.View, trekState *trekStateType) error {
+	trekState.theme.highlight(view)
+	view.Editable = false
+	view.Wrap = false
+
+	alloc, err := trekState.CurrentAllocation()
+
+	if err == nil {
+		var task *nomad.Task
+		task, err = trekState.CurrentTask()
+		var provider taskFormatProvider
+		if err == nil {
+			provider, err = buildTaskDetails(alloc, task)
+		}
+		if err == nil {
+			err = trekPrintDetails(view, taskDetailsFormat, provider)
+		}
+	}
 	if err != nil {
-		msg = "failed (%+v)"
-	} else {
-		msg = "is done"
+		fmt.Fprintln(view, err)
 	}
+	return nil
+}
+
+func setSelectedCluster(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedClusterIndex = trekState.filteredItem("Clusters", position.y)
+}
+
+func setSelectedJob(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedJob = trekState.filteredItem("Jobs", position.y)
+}
 
-	return openPopup(g, v, trekState, fmt.Sprintf("Garbage collection %s\n", msg))
+func setSelectedTaskGroup(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedAllocationGroup = trekState.filteredItem("Task Groups", position.y)
+}
+
+func setSelectedAllocation(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedAllocationIndex = trekState.filteredItem("Allocations", position.y)
+}
+
+func setSelectedTask(trekState *trekStateType, position cursorPosition) {
+	trekState.selectedTask = trekState.filteredItem("Tasks", position.y)
+}
+
+func garbageCollect(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+	return confirmIfRequired(g, v, trekState, "Run a garbage collection?", func(g *gocui.Gui, trekState *trekStateType) error {
+		var msg string
+		err := trekState.client.System().GarbageCollect()
+		if err != nil {
+			msg = "failed (%+v)"
+		} else {
+			msg = "is done"
+		}
+
+		return openPopup(g, v, trekState, fmt.Sprintf("Garbage collection %s\n", msg))
+	})
 }
 
 func refreshUI(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
@@ -384,6 +621,8 @@ func deleteView(currentView string, newCurrentView string, handler deleteViewCal
 		if err := g.DeleteView(currentView); err != nil {
 			return err
 		}
+		trekState.stopWatch(currentView)
+		delete(trekState.filters, currentView)
 		if _, err := g.SetCurrentView(newCurrentView); err != nil {
 			return err
 		}
@@ -394,90 +633,260 @@ func deleteView(currentView string, newCurrentView string, handler deleteViewCal
 }
 
 var bindings = []binding{
-	binding{panelName: "Clusters", key: gocui.KeyEnter, handler: selectCluster},
-	binding{panelName: "Clusters", key: gocui.KeyArrowRight, handler: selectCluster},
-	binding{panelName: "Clusters", key: gocui.KeyArrowDown, handler: cursorDown(
-		func(trekState *trekStateType, position cursorPosition) { trekState.selectedClusterIndex = position.y },
-		func(trekState *trekStateType) int { return len(*trekState.nomadConnectConfiguration.Environments) })},
-	binding{panelName: "Clusters", key: gocui.KeyArrowUp,
-		handler: cursorUp(func(trekState *trekStateType, position cursorPosition) {
-			trekState.selectedClusterIndex = position.y
-		})},
-
-	binding{panelName: "Jobs", key: gocui.KeyArrowLeft,
+	binding{panelName: "Clusters", key: gocui.KeyEnter, action: "select", handler: withSelection(selectCluster)},
+	binding{panelName: "Clusters", key: gocui.KeyArrowRight, action: "select", handler: withSelection(selectCluster)},
+	binding{panelName: "Clusters", key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedCluster,
+		func(trekState *trekStateType) int { return trekState.visibleLines("Clusters") })},
+	binding{panelName: "Clusters", key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedCluster)},
+	binding{panelName: "Clusters", key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedCluster, -listPage)},
+	binding{panelName: "Clusters", key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedCluster, listPage)},
+	binding{panelName: "Clusters", key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedCluster, listTop)},
+	binding{panelName: "Clusters", key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedCluster, listBottom)},
+	binding{panelName: "Clusters", key: '/', action: "filter", handler: filterList(renderClusters, setSelectedCluster)},
+	binding{panelName: "Clusters", key: 'o', action: "nodes", handler: withSelection(selectNodes)},
+
+	binding{panelName: nodesViewName, key: gocui.KeyArrowLeft, action: "back",
+		handler: deleteView(nodesViewName, "Clusters", func(trekState *trekStateType) { trekState.selectedNode = 0 })},
+	binding{panelName: nodesViewName, key: gocui.KeyEnter, action: "select", handler: withSelection(selectNode)},
+	binding{panelName: nodesViewName, key: gocui.KeyArrowRight, action: "select", handler: withSelection(selectNode)},
+	binding{panelName: nodesViewName, key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedNode)},
+	binding{panelName: nodesViewName, key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedNode, -listPage)},
+	binding{panelName: nodesViewName, key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedNode, listPage)},
+	binding{panelName: nodesViewName, key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedNode, listTop)},
+	binding{panelName: nodesViewName, key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedNode, listBottom)},
+	binding{panelName: nodesViewName, key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedNode,
+		func(trekState *trekStateType) int { return trekState.visibleLines(nodesViewName) })},
+	binding{panelName: nodesViewName, key: '/', action: "filter", handler: filterList(renderNodes, setSelectedNode)},
+	binding{panelName: nodesViewName, key: 'a', action: "allocations", handler: withSelection(selectNodeAllocations)},
+
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyArrowLeft, action: "back",
+		handler: deleteView(nodeAllocationsViewName, nodesViewName, func(trekState *trekStateType) { trekState.selectedNodeAllocation = 0 })},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyEnter, action: "select", handler: withSelection(openNodeAllocation)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyArrowRight, action: "select", handler: withSelection(openNodeAllocation)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedNodeAllocation)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedNodeAllocation, -listPage)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedNodeAllocation, listPage)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedNodeAllocation, listTop)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedNodeAllocation, listBottom)},
+	binding{panelName: nodeAllocationsViewName, key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedNodeAllocation,
+		func(trekState *trekStateType) int { return trekState.visibleLines(nodeAllocationsViewName) })},
+	binding{panelName: nodeAllocationsViewName, key: '/', action: "filter", handler: filterList(renderNodeAllocations, setSelectedNodeAllocation)},
+
+	binding{panelName: nodeViewName, key: gocui.KeyEnter, action: "close",
+		handler: deleteView(nodeViewName, nodesViewName, func(trekState *trekStateType) {})},
+	binding{panelName: nodeViewName, key: gocui.KeyArrowLeft, action: "close",
+		handler: deleteView(nodeViewName, nodesViewName, func(trekState *trekStateType) {})},
+	binding{panelName: nodeViewName, key: gocui.KeyArrowUp, action: "up", handler: scrollPanel(-1)},
+	binding{panelName: nodeViewName, key: gocui.KeyArrowDown, action: "down", handler: scrollPanel(1)},
+	binding{panelName: nodeViewName, key: gocui.KeyPgup, action: "page-up", handler: scrollPanel(-20)},
+	binding{panelName: nodeViewName, key: gocui.KeyPgdn, action: "page-down", handler: scrollPanel(20)},
+
+	binding{panelName: "Jobs", key: gocui.KeyArrowLeft, action: "back",
 		handler: deleteView("Jobs", "Clusters", func(trekState *trekStateType) { trekState.selectedJob = 0 })},
-	binding{panelName: "Jobs", key: gocui.KeyEnter, handler: selectJob},
-	binding{panelName: "Jobs", key: gocui.KeyArrowRight, handler: selectJob},
-	binding{panelName: "Jobs", key: gocui.KeyArrowUp,
-		handler: cursorUp(func(trekState *trekStateType, position cursorPosition) {
-			trekState.selectedJob = position.y
-		})},
-	binding{panelName: "Jobs", key: gocui.KeyArrowDown, handler: cursorDown(
-		func(trekState *trekStateType, position cursorPosition) { trekState.selectedJob = position.y },
-		func(trekState *trekStateType) int { return len(trekState.Jobs()) })},
-
-	binding{panelName: "Task Groups", key: gocui.KeyArrowLeft,
+	binding{panelName: "Jobs", key: gocui.KeyEnter, action: "select", handler: withSelection(selectJob)},
+	binding{panelName: "Jobs", key: gocui.KeyArrowRight, action: "select", handler: withSelection(selectJob)},
+	binding{panelName: "Jobs", key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedJob)},
+	binding{panelName: "Jobs", key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedJob, -listPage)},
+	binding{panelName: "Jobs", key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedJob, listPage)},
+	binding{panelName: "Jobs", key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedJob, listTop)},
+	binding{panelName: "Jobs", key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedJob, listBottom)},
+	binding{panelName: "Jobs", key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedJob,
+		func(trekState *trekStateType) int { return trekState.visibleLines("Jobs") })},
+	binding{panelName: "Jobs", key: '/', action: "filter", handler: filterList(renderJobs, setSelectedJob)},
+	binding{panelName: "Jobs", key: 'x', action: "stop", handler: mutating(stopJob(false))},
+	binding{panelName: "Jobs", key: 'X', action: "purge", handler: mutating(stopJob(true))},
+	binding{panelName: "Jobs", key: 'F', action: "force-launch", handler: mutating(forcePeriodicJob)},
+	binding{panelName: "Jobs", key: 'D', action: "dispatch", handler: mutating(dispatchJob)},
+
+	binding{panelName: "Task Groups", key: gocui.KeyArrowLeft, action: "back",
 		handler: deleteView("Task Groups", "Jobs", func(trekState *trekStateType) { trekState.selectedAllocationGroup = 0 })},
-	binding{panelName: "Task Groups", key: gocui.KeyEnter, handler: selectTaskGroup},
-	binding{panelName: "Task Groups", key: gocui.KeyArrowRight, handler: selectTaskGroup},
-	binding{panelName: "Task Groups", key: gocui.KeyArrowDown, handler: cursorDown(
-		func(trekState *trekStateType, position cursorPosition) {
-			trekState.selectedAllocationGroup = position.y
-		},
-		func(trekState *trekStateType) int { return len(trekState.CurrentTaskGroups()) })},
-	binding{panelName: "Task Groups", key: gocui.KeyArrowUp,
-		handler: cursorUp(func(trekState *trekStateType, position cursorPosition) {
-			trekState.selectedAllocationGroup = position.y
-		})},
-
-	binding{panelName: "Allocations", key: gocui.KeyArrowLeft,
+	binding{panelName: "Task Groups", key: gocui.KeyEnter, action: "select", handler: withSelection(selectTaskGroup)},
+	binding{panelName: "Task Groups", key: gocui.KeyArrowRight, action: "select", handler: withSelection(selectTaskGroup)},
+	binding{panelName: "Task Groups", key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedTaskGroup,
+		func(trekState *trekStateType) int { return trekState.visibleLines("Task Groups") })},
+	binding{panelName: "Task Groups", key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedTaskGroup)},
+	binding{panelName: "Task Groups", key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedTaskGroup, -listPage)},
+	binding{panelName: "Task Groups", key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedTaskGroup, listPage)},
+	binding{panelName: "Task Groups", key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedTaskGroup, listTop)},
+	binding{panelName: "Task Groups", key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedTaskGroup, listBottom)},
+	binding{panelName: "Task Groups", key: '/', action: "filter", handler: filterList(renderTaskGroups, setSelectedTaskGroup)},
+	binding{panelName: "Task Groups", key: 'd', action: "deployments", handler: selectDeployments},
+	binding{panelName: "Task Groups", key: 'e', action: "evaluations", handler: selectEvaluations},
+	binding{panelName: "Task Groups", key: 'v', action: "spec", handler: showSpec},
+	binding{panelName: "Task Groups", key: 'p', action: "plan", handler: showPlan},
+	binding{panelName: "Task Groups", key: 'c', action: "scale", handler: mutating(scaleTaskGroup)},
+
+	binding{panelName: deploymentsViewName, key: gocui.KeyArrowLeft, action: "back",
+		handler: deleteView(deploymentsViewName, "Task Groups", func(trekState *trekStateType) { trekState.selectedDeployment = 0 })},
+	binding{panelName: deploymentsViewName, key: gocui.KeyEnter, action: "select", handler: selectDeployment},
+	binding{panelName: deploymentsViewName, key: gocui.KeyArrowRight, action: "select", handler: selectDeployment},
+	binding{panelName: deploymentsViewName, key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedDeployment)},
+	binding{panelName: deploymentsViewName, key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedDeployment, -listPage)},
+	binding{panelName: deploymentsViewName, key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedDeployment, listPage)},
+	binding{panelName: deploymentsViewName, key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedDeployment, listTop)},
+	binding{panelName: deploymentsViewName, key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedDeployment, listBottom)},
+	binding{panelName: deploymentsViewName, key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedDeployment,
+		func(trekState *trekStateType) int { return len(trekState.deployments) })},
+
+	binding{panelName: deploymentViewName, key: gocui.KeyEnter, action: "close",
+		handler: deleteView(deploymentViewName, deploymentsViewName, func(trekState *trekStateType) {})},
+	binding{panelName: deploymentViewName, key: gocui.KeyArrowLeft, action: "close",
+		handler: deleteView(deploymentViewName, deploymentsViewName, func(trekState *trekStateType) {})},
+	binding{panelName: deploymentViewName, key: 'p', action: "promote", handler: mutating(promoteDeployment)},
+	binding{panelName: deploymentViewName, key: 'f', action: "fail", handler: mutating(failDeployment)},
+	binding{panelName: deploymentViewName, key: 's', action: "pause", handler: mutating(toggleDeploymentPause)},
+
+	binding{panelName: evaluationsViewName, key: gocui.KeyArrowLeft, action: "back",
+		handler: deleteView(evaluationsViewName, "Task Groups", func(trekState *trekStateType) { trekState.selectedEvaluation = 0 })},
+	binding{panelName: evaluationsViewName, key: gocui.KeyEnter, action: "select", handler: selectEvaluation},
+	binding{panelName: evaluationsViewName, key: gocui.KeyArrowRight, action: "select", handler: selectEvaluation},
+	binding{panelName: evaluationsViewName, key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedEvaluation)},
+	binding{panelName: evaluationsViewName, key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedEvaluation, -listPage)},
+	binding{panelName: evaluationsViewName, key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedEvaluation, listPage)},
+	binding{panelName: evaluationsViewName, key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedEvaluation, listTop)},
+	binding{panelName: evaluationsViewName, key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedEvaluation, listBottom)},
+	binding{panelName: evaluationsViewName, key: gocui.KeyArrowDown, action: "down", handler: cursorDown(setSelectedEvaluation,
+		func(trekState *trekStateType) int { return len(trekState.evaluations) })},
+
+	binding{panelName: evaluationViewName, key: gocui.KeyEnter, action: "close",
+		handler: deleteView(evaluationViewName, evaluationsViewName, func(trekState *trekStateType) {})},
+	binding{panelName: evaluationViewName, key: gocui.KeyArrowLeft, action: "close",
+		handler: deleteView(evaluationViewName, evaluationsViewName, func(trekState *trekStateType) {})},
+	binding{panelName: evaluationViewName, key: gocui.KeyArrowUp, action: "up", handler: scrollPanel(-1)},
+	binding{panelName: evaluationViewName, key: gocui.KeyArrowDown, action: "down", handler: scrollPanel(1)},
+	binding{panelName: evaluationViewName, key: gocui.KeyPgup, action: "page-up", handler: scrollPanel(-20)},
+	binding{panelName: evaluationViewName, key: gocui.KeyPgdn, action: "page-down", handler: scrollPanel(20)},
+
+	binding{panelName: "Allocations", key: gocui.KeyArrowLeft, action: "back",
 		handler: deleteView("Allocations", "Task Groups", func(trekState *trekStateType) {
 			trekState.selectedAllocationIndex = 0
 		})},
-	binding{panelName: "Allocations", key: gocui.KeyEnter, handler: selectAllocation},
-	binding{panelName: "Allocations", key: gocui.KeyArrowRight, handler: selectAllocation},
-	binding{panelName: "Allocations", key: gocui.KeyArrowDown,
-		handler: cursorDown(
-			func(trekState *trekStateType, position cursorPosition) {
-				trekState.selectedAllocationIndex = position.y
-			},
+	binding{panelName: "Allocations", key: gocui.KeyEnter, action: "select", handler: withSelection(selectAllocation)},
+	binding{panelName: "Allocations", key: gocui.KeyArrowRight, action: "select", handler: withSelection(selectAllocation)},
+	binding{panelName: "Allocations", key: gocui.KeyArrowDown, action: "down",
+		handler: cursorDown(setSelectedAllocation,
 			func(trekState *trekStateType) int {
-				return len(trekState.CurrentAllocations())
+				return trekState.visibleLines("Allocations")
 			})},
-	binding{panelName: "Allocations", key: gocui.KeyArrowUp,
-		handler: cursorUp(func(trekState *trekStateType, position cursorPosition) {
-			trekState.selectedAllocationIndex = position.y
-		})},
-
-	binding{panelName: "Tasks", key: gocui.KeyArrowLeft,
+	binding{panelName: "Allocations", key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedAllocation)},
+	binding{panelName: "Allocations", key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedAllocation, -listPage)},
+	binding{panelName: "Allocations", key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedAllocation, listPage)},
+	binding{panelName: "Allocations", key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedAllocation, listTop)},
+	binding{panelName: "Allocations", key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedAllocation, listBottom)},
+	binding{panelName: "Allocations", key: '/', action: "filter", handler: filterList(renderAllocations, setSelectedAllocation)},
+	binding{panelName: "Allocations", key: 's', action: "status-filter", handler: cycleAllocationStatusFilter},
+	binding{panelName: "Allocations", key: 'f', action: "files", handler: browseFiles(false)},
+	binding{panelName: "Allocations", key: 'u', action: "stats", handler: showStats},
+	binding{panelName: "Allocations", key: 'r', action: "restart", handler: mutating(restartAllocation)},
+	binding{panelName: "Allocations", key: 'x', action: "stop", handler: mutating(stopAllocation)},
+
+	binding{panelName: "Tasks", key: gocui.KeyArrowLeft, action: "back",
 		handler: deleteView("Tasks", "Allocations", func(trekState *trekStateType) { trekState.selectedTask = 0 })},
-	binding{panelName: "Tasks", key: gocui.KeyEnter, handler: selectTask},
-	binding{panelName: "Tasks", key: gocui.KeyArrowRight, handler: selectTask},
-	binding{panelName: "Tasks", key: gocui.KeyArrowDown,
-		handler: cursorDown(
-			func(trekState *trekStateType, position cursorPosition) { trekState.selectedTask = position.y },
+	binding{panelName: "Tasks", key: gocui.KeyEnter, action: "select", handler: withSelection(selectTask)},
+	binding{panelName: "Tasks", key: gocui.KeyArrowRight, action: "select", handler: withSelection(selectTask)},
+	binding{panelName: "Tasks", key: gocui.KeyArrowDown, action: "down",
+		handler: cursorDown(setSelectedTask,
 			func(trekState *trekStateType) int {
-				return len(trekState.CurrentTaskGroups()[trekState.selectedAllocationGroup].Tasks)
+				return trekState.visibleLines("Tasks")
 			})},
-	binding{panelName: "Tasks", key: gocui.KeyArrowUp,
-		handler: cursorUp(func(trekState *trekStateType, position cursorPosition) {
-			trekState.selectedTask = position.y
-		})},
-
-	binding{panelName: "Task", key: gocui.KeyEnter,
+	binding{panelName: "Tasks", key: gocui.KeyArrowUp, action: "up", handler: cursorUp(setSelectedTask)},
+	binding{panelName: "Tasks", key: gocui.KeyPgup, action: "page-up", handler: moveInList(setSelectedTask, -listPage)},
+	binding{panelName: "Tasks", key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setSelectedTask, listPage)},
+	binding{panelName: "Tasks", key: gocui.KeyHome, action: "top", handler: moveInList(setSelectedTask, listTop)},
+	binding{panelName: "Tasks", key: gocui.KeyEnd, action: "bottom", handler: moveInList(setSelectedTask, listBottom)},
+	binding{panelName: "Tasks", key: '/', action: "filter", handler: filterList(renderTasks, setSelectedTask)},
+	binding{panelName: "Tasks", key: 'f', action: "files", handler: withSelection(browseFiles(true))},
+	binding{panelName: "Tasks", key: 'u', action: "stats", handler: withSelection(showStats)},
+
+	binding{panelName: "Task", key: gocui.KeyEnter, action: "close",
 		handler: deleteView("Task", "Tasks", func(trekState *trekStateType) {})},
-
-	binding{panelName: "", key: gocui.KeyCtrlC, handler: quit},
-	binding{panelName: "", key: gocui.KeyF12, handler: quit},
-	binding{panelName: "", key: gocui.KeyF2, handler: garbageCollect},
-	binding{panelName: "", key: gocui.KeyF5, handler: refreshUI},
-	binding{panelName: "popup", key: gocui.KeyEnter, handler: dismissPopup()},
-	binding{panelName: "msg", key: gocui.KeyEnter,
+	binding{panelName: "Task", key: 'l', action: "logs", handler: showLogs},
+	binding{panelName: "Task", key: 'e', action: "exec", handler: mutating(execIntoTask)},
+	binding{panelName: "Task", key: 'u', action: "stats", handler: showStats},
+
+	binding{panelName: logsViewName, key: gocui.KeyEnter, action: "close", handler: closeLogs},
+	binding{panelName: logsViewName, key: gocui.KeyArrowLeft, action: "close", handler: closeLogs},
+	binding{panelName: logsViewName, key: gocui.KeyArrowUp, action: "up", handler: scrollLogs(-1)},
+	binding{panelName: logsViewName, key: gocui.KeyArrowDown, action: "down", handler: scrollLogs(1)},
+	binding{panelName: logsViewName, key: gocui.KeyPgup, action: "page-up", handler: scrollLogs(-20)},
+	binding{panelName: logsViewName, key: gocui.KeyPgdn, action: "page-down", handler: scrollLogs(20)},
+	binding{panelName: logsViewName, key: 'f', action: "follow", handler: toggleLogFollow},
+	binding{panelName: logsViewName, key: 's', action: "stream", handler: toggleLogStream},
+	binding{panelName: logsViewName, key: '/', action: "search", handler: searchLogs},
+	binding{panelName: logsViewName, key: 'n', action: "next-match", handler: nextLogMatch},
+	binding{panelName: logsViewName, key: 'N', action: "previous-match", handler: previousLogMatch},
+
+	binding{panelName: filesViewName, key: gocui.KeyEnter, action: "open", handler: openFileEntry},
+	binding{panelName: filesViewName, key: gocui.KeyArrowRight, action: "open", handler: openFileEntry},
+	binding{panelName: filesViewName, key: gocui.KeyArrowLeft, action: "parent", handler: leaveFileEntry},
+	binding{panelName: filesViewName, key: gocui.KeyEsc, action: "close", handler: closeFiles},
+	binding{panelName: filesViewName, key: gocui.KeyArrowUp, action: "up", handler: moveInFiles(-1)},
+	binding{panelName: filesViewName, key: gocui.KeyArrowDown, action: "down", handler: moveInFiles(1)},
+	binding{panelName: filesViewName, key: gocui.KeyPgup, action: "page-up", handler: moveInFiles(-20)},
+	binding{panelName: filesViewName, key: gocui.KeyPgdn, action: "page-down", handler: moveInFiles(20)},
+
+	binding{panelName: statsViewName, key: gocui.KeyEnter, action: "close", handler: closeStats},
+	binding{panelName: statsViewName, key: gocui.KeyArrowLeft, action: "close", handler: closeStats},
+	binding{panelName: statsViewName, key: gocui.KeyEsc, action: "close", handler: closeStats},
+
+	binding{panelName: specViewName, key: gocui.KeyEnter, action: "close", handler: closeSpec},
+	binding{panelName: specViewName, key: gocui.KeyArrowLeft, action: "close", handler: closeSpec},
+	binding{panelName: specViewName, key: gocui.KeyEsc, action: "close", handler: closeSpec},
+	binding{panelName: specViewName, key: gocui.KeyArrowUp, action: "up", handler: scrollPanel(-1)},
+	binding{panelName: specViewName, key: gocui.KeyArrowDown, action: "down", handler: scrollPanel(1)},
+	binding{panelName: specViewName, key: gocui.KeyPgup, action: "page-up", handler: scrollPanel(-20)},
+	binding{panelName: specViewName, key: gocui.KeyPgdn, action: "page-down", handler: scrollPanel(20)},
+	binding{panelName: specViewName, key: 'h', action: "format", handler: toggleSpecFormat},
+
+	binding{panelName: planViewName, key: gocui.KeyEnter, action: "close", handler: closePlan},
+	binding{panelName: planViewName, key: gocui.KeyArrowLeft, action: "close", handler: closePlan},
+	binding{panelName: planViewName, key: gocui.KeyEsc, action: "close", handler: closePlan},
+	binding{panelName: planViewName, key: gocui.KeyArrowUp, action: "up", handler: scrollPanel(-1)},
+	binding{panelName: planViewName, key: gocui.KeyArrowDown, action: "down", handler: scrollPanel(1)},
+	binding{panelName: planViewName, key: gocui.KeyPgup, action: "page-up", handler: scrollPanel(-20)},
+	binding{panelName: planViewName, key: gocui.KeyPgdn, action: "page-down", handler: scrollPanel(20)},
+
+	binding{panelName: promptViewName, key: gocui.KeyEnter, action: "submit", handler: submitPrompt},
+	binding{panelName: promptViewName, key: gocui.KeyEsc, action: "cancel", handler: cancelPrompt},
+
+	binding{panelName: confirmViewName, key: 'y', action: "confirm", handler: acceptConfirm},
+	binding{panelName: confirmViewName, key: 'n', action: "cancel", handler: rejectConfirm},
+	binding{panelName: confirmViewName, key: gocui.KeyEsc, action: "cancel", handler: rejectConfirm},
+	binding{panelName: confirmViewName, key: gocui.KeyEnter, action: "cancel", handler: rejectConfirm},
+
+	binding{panelName: "", key: gocui.KeyF1, action: "help", handler: showHelp},
+	binding{panelName: "", key: gocui.KeyF12, action: "quit", handler: quit},
+	binding{panelName: "", key: gocui.KeyCtrlC, action: "quit", handler: quit},
+	binding{panelName: "", key: gocui.KeyF2, action: "gc", handler: mutating(garbageCollect)},
+	binding{panelName: "", key: gocui.KeyF3, action: "debug", handler: showInspector},
+	binding{panelName: "", key: gocui.KeyF5, action: "refresh", handler: refreshUI},
+
+	binding{panelName: inspectorViewName, key: gocui.KeyEnter, action: "toggle", handler: toggleNode},
+	binding{panelName: inspectorViewName, key: gocui.KeySpace, action: "toggle", handler: toggleNode},
+	binding{panelName: inspectorViewName, key: gocui.KeyArrowRight, action: "toggle", handler: toggleNode},
+	binding{panelName: inspectorViewName, key: gocui.KeyArrowLeft, action: "collapse", handler: collapseNode},
+	binding{panelName: inspectorViewName, key: gocui.KeyEsc, action: "close", handler: closeInspector},
+	binding{panelName: inspectorViewName, key: gocui.KeyArrowUp, action: "up", handler: moveInList(setInspectorCursor, -1)},
+	binding{panelName: inspectorViewName, key: gocui.KeyArrowDown, action: "down", handler: moveInList(setInspectorCursor, 1)},
+	binding{panelName: inspectorViewName, key: gocui.KeyPgup, action: "page-up", handler: moveInList(setInspectorCursor, -listPage)},
+	binding{panelName: inspectorViewName, key: gocui.KeyPgdn, action: "page-down", handler: moveInList(setInspectorCursor, listPage)},
+	binding{panelName: inspectorViewName, key: gocui.KeyHome, action: "top", handler: moveInList(setInspectorCursor, listTop)},
+	binding{panelName: inspectorViewName, key: gocui.KeyEnd, action: "bottom", handler: moveInList(setInspectorCursor, listBottom)},
+
+	binding{panelName: helpViewName, key: gocui.KeyEnter, action: "close", handler: closeHelp},
+	binding{panelName: helpViewName, key: gocui.KeyArrowLeft, action: "close", handler: closeHelp},
+	binding{panelName: helpViewName, key: gocui.KeyEsc, action: "close", handler: closeHelp},
+	binding{panelName: helpViewName, key: gocui.KeyArrowUp, action: "up", handler: scrollPanel(-1)},
+	binding{panelName: helpViewName, key: gocui.KeyArrowDown, action: "down", handler: scrollPanel(1)},
+	binding{panelName: helpViewName, key: gocui.KeyPgup, action: "page-up", handler: scrollPanel(-20)},
+	binding{panelName: helpViewName, key: gocui.KeyPgdn, action: "page-down", handler: scrollPanel(20)},
+	binding{panelName: "popup", key: gocui.KeyEnter, action: "close", handler: dismissPopup()},
+	binding{panelName: "msg", key: gocui.KeyEnter, action: "close",
 		handler: deleteView("msg", "Allocations", func(trekState *trekStateType) {})},
 }
 
 func keybindings(g *gocui.Gui, trekState *trekStateType) error {
-	for _, binding := range bindings {
+	for _, binding := range trekState.bindings {
 		if err := g.SetKeybinding(binding.panelName, binding.key, gocui.ModNone, stateify(binding.handler, trekState)); err != nil {
 			return err
 		}
@@ -513,6 +922,9 @@ func getBounds(maxX int, maxY int, currentPanel int, totalPanels int, margin int
 func layout(trekState *trekStateType) layoutType {
 	return func(g *gocui.Gui) error {
 		title := "Trek"
+		if trekState.readOnly {
+			title += " [read-only]"
+		}
 
 		// Show menu
 		maxX, _ := g.Size()
@@ -520,38 +932,42 @@ func layout(trekState *trekStateType) layoutType {
 		startY := -1 // no frame
 		endX := maxX - 1
 		endY := 1
-		offset := 0
+		offset := len(title) + 6
 		if v, err := g.SetView("title_view", startX, startY, endX, endY); err != nil {
 			if err != gocui.ErrUnknownView {
 				return err
 			}
 			v.Highlight = true
 			v.Frame = false
-			v.SelBgColor = gocui.ColorBlue
-			v.SelFgColor = gocui.ColorBlack
+			v.SelFgColor = trekState.theme.title.foreground.attribute()
+			v.SelBgColor = trekState.theme.title.background.attribute()
 			fmt.Fprintf(v, "%s", title)
-			offset += len(title)
 		}
 
-		offset += 6
-		menuItems := []string{"F1:DEBUG", "F2:GC", "F5:REFRESH", "F12:EXIT"}
+		// the menu follows the focused panel
+		panel := ""
+		if current := g.CurrentView(); current != nil {
+			panel = current.Name()
+		}
 
-		if v, err := g.SetView("menu_items", startX+offset, startY, endX, endY); err != nil {
+		v, err := g.SetView("menu_items", startX+offset, startY, endX, endY)
+		if err != nil {
 			if err != gocui.ErrUnknownView {
 				return err
 			}
 			v.Highlight = false
 			v.Frame = false
-			v.BgColor = gocui.ColorGreen
-			v.FgColor = gocui.ColorBlack
+			v.FgColor = trekState.theme.menu.foreground.attribute()
+			v.BgColor = trekState.theme.menu.background.attribute()
+		}
+		v.Clear()
 
-			fmt.Fprintf(v, " ")
-			for index, optionName := range menuItems {
-				if index > 0 {
-					fmt.Fprintf(v, " | ")
-				}
-				fmt.Fprintf(v, "%s", optionName)
+		fmt.Fprintf(v, " ")
+		for index, optionName := range menuItems(trekState.bindings, panel) {
+			if index > 0 {
+				fmt.Fprintf(v, " | ")
 			}
+			fmt.Fprintf(v, "%s", optionName)
 		}
 		return nil
 	}
@@ -572,68 +988,197 @@ func listClusters(gui *gocui.Gui, trekState *trekStateType) error {
 			panelNum:                0,
 			panelsTotal:             5,
 			margin:                  0,
-			handler: func(view *gocui.View, trekState *trekStateType) error {
-
-				view.Highlight = true
-				view.SelBgColor = gocui.ColorGreen
-				view.SelFgColor = gocui.ColorBlack
-				file, err := os.Open(".trek.rc")
-
-				if err != nil {
-					// Can't find configuration file, applying default configuration
-					address := os.Getenv("NOMAD_ADDR")
-					if address == "" {
-						// Defaulting on localhost
-						address = "http://localhost:4646"
-					}
-					trekState.nomadConnectConfiguration.addEnvironment("default", address)
-				} else {
-
-					decoder := json.NewDecoder(file)
-					trekState.nomadConnectConfiguration = configuration{}
-					err = decoder.Decode(&trekState.nomadConnectConfiguration)
-					if err != nil {
-						log.Panicln(err)
-					}
-
-				}
-
-				for _, env := range *trekState.nomadConnectConfiguration.Environments {
-					fmt.Fprintf(view, "%s\n", (env).Name)
-				}
-
-				return nil
-			},
+			handler:                 renderClusters,
 		},
 		trekState,
 	)
 }
 
-func runUI(options trekOptions) {
-	trekState := new(trekStateType)
+// readConfiguration reads .trek.rc, returning nil when there is none
+func readConfiguration() (*configuration, error) {
+	file, err := os.Open(".trek.rc")
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
 
-	// build ui
-	g, err := gocui.NewGui(gocui.OutputNormal)
+	config := new(configuration)
+	if err := json.NewDecoder(file).Decode(config); err != nil {
+		return nil, newTrekError(GenericError, err, "cannot parse .trek.rc")
+	}
+	return config, nil
+}
+
+// renderClusters lists the environments of .trek.rc, or the default one
+func renderClusters(view *gocui.View, trekState *trekStateType) error {
+	view.Highlight = true
+	trekState.theme.highlight(view)
+	config, err := readConfiguration()
 	if err != nil {
-		log.Panicln(err)
+		return err
+	}
+
+	if config == nil {
+		// Can't find configuration file, applying default configuration
+		address := os.Getenv("NOMAD_ADDR")
+		if address == "" {
+			// Defaulting on localhost
+			address = "http://localhost:4646"
+		}
+		env := trekState.defaultEnvironment
+		env.Address = address
+		trekState.nomadConnectConfiguration = configuration{}
+		trekState.nomadConnectConfiguration.addEnvironment(env)
+	} else {
+		trekState.nomadConnectConfiguration = *config
+	}
+
+	lines := make([]string, 0)
+	for _, env := range *trekState.nomadConnectConfiguration.Environments {
+		line := env.Name
+		if scope := env.Scope(); scope != "" {
+			line = fmt.Sprintf("%s (%s)", line, scope)
+		}
+		if trekState.readOnly || env.ReadOnly {
+			line += " " + trekState.theme.paint("read-only", "[read-only]")
+		} else if env.RequireConfirmation {
+			line += " " + trekState.theme.paint("confirm", "[confirm]")
+		}
+		lines = append(lines, line)
+	}
+	renderList(view, trekState, "Clusters", lines)
+
+	return nil
+}
+
+// restoreViews rebuilds the panels that were open before the UI was
+// suspended, keeping their selection
+func restoreViews(g *gocui.Gui, trekState *trekStateType) {
+	views := trekState.activeViews
+
+	// The first view lists clusters, and doesn't track itself
+	trekState.activeViews = views[:1]
+	views[0](g, nil, trekState)
+	scrollToSelection(g, "Clusters", trekState.filteredLine("Clusters", trekState.selectedClusterIndex))
+
+	selections := map[string]func() int{
+		"Jobs":                  func() int { return trekState.selectedJob },
+		"Task Groups":           func() int { return trekState.selectedAllocationGroup },
+		"Allocations":           func() int { return trekState.selectedAllocationIndex },
+		"Tasks":                 func() int { return trekState.selectedTask },
+		nodesViewName:           func() int { return trekState.selectedNode },
+		nodeAllocationsViewName: func() int { return trekState.selectedNodeAllocation },
+		deploymentsViewName:     func() int { return trekState.selectedDeployment },
+		evaluationsViewName:     func() int { return trekState.selectedEvaluation },
+	}
+	for _, viewHandler := range views[1:] {
+		viewHandler(g, nil, trekState)
+		if v := g.CurrentView(); v != nil {
+			if selected, ok := selections[v.Name()]; ok {
+				scrollToSelection(g, v.Name(), trekState.filteredLine(v.Name(), selected()))
+			}
+		}
+	}
+}
+
+func scrollToSelection(g *gocui.Gui, viewName string, selected int) {
+	if v, err := g.View(viewName); err == nil {
+		scrollTo(v, selected)
+	}
+}
+
+func (trekState *trekStateType) stopWatches() {
+	for viewName := range trekState.watchers {
+		trekState.stopWatch(viewName)
+	}
+}
+
+// runGui builds the UI and runs it until the user quits or asks for an exec
+// session
+func runGui(trekState *trekStateType) error {
+	g, err := gocui.NewGui(trekState.theme.output)
+	if err != nil {
+		return err
 	}
 	defer g.Close()
+	trekState.theme.apply(g)
+	defer trekState.stopWatches()
 
 	g.Cursor = false
+	g.InputEsc = true
 
 	g.SetManagerFunc(layout(trekState))
 
-	trekState.trackView(
-		func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
-			return listClusters(g, trekState)
-		})
-	listClusters(g, trekState)
+	if len(trekState.activeViews) == 0 {
+		trekState.trackView(
+			func(g *gocui.Gui, v *gocui.View, trekState *trekStateType) error {
+				return listClusters(g, trekState)
+			})
+		if err := listClusters(g, trekState); err != nil {
+			return err
+		}
+	} else {
+		restoreViews(g, trekState)
+	}
 
 	if err := keybindings(g, trekState); err != nil {
-		log.Panicln(err)
+		return err
 	}
 
-	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
-		log.Panicln(err)
+	if execRequest := trekState.pendingExec; execRequest != nil {
+		trekState.pendingExec = nil
+		if v := g.CurrentView(); v != nil {
+			openPopup(g, v, trekState, execRequest.result)
+		}
+	}
+
+	return g.MainLoop()
+}
+
+// runUI runs the UI until the user quits.  Errors of the configuration, and the
+// ones stopping the UI, are returned.
+func runUI(options trekOptions) error {
+	trekState := new(trekStateType)
+	trekState.defaultEnvironment = options.defaultEnvironment()
+	trekState.allocationStatusFilter = options.allocStatuses
+	trekState.readOnly = options.readOnly
+	trekState.requests = new(requestLog)
+
+	config, err := readConfiguration()
+	if err != nil {
+		return err
+	}
+	var keys *keymap
+	var themeSettings *themeConfig
+	if config != nil {
+		keys = config.Keys
+		themeSettings = config.Theme
+	}
+	if trekState.bindings, err = resolveBindings(bindings, keys); err != nil {
+		return newTrekError(GenericError, err, "invalid keymap in .trek.rc")
+	}
+	if trekState.theme, err = resolveTheme(themeSettings, options.theme); err != nil {
+		return newTrekError(GenericError, err, "invalid theme")
+	}
+
+	for {
+		err := runGui(trekState)
+		if err == errExecSession && trekState.pendingExec != nil {
+			request := trekState.pendingExec
+			code, err := execTask(trekState.directClient, &request.alloc, request.task, request.command)
+			if err != nil {
+				request.result = err.Error()
+			} else {
+				request.result = fmt.Sprintf("%s exited with code %d", strings.Join(request.command, " "), code)
+			}
+			continue
+		}
+		if err == nil || err == gocui.ErrQuit {
+			return nil
+		}
+		if _, ok := err.(*trekError); ok {
+			return err
+		}
+		return newTrekError(GenericError, err, "the UI stopped")
 	}
 }
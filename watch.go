@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	// watchWaitTime bounds how long a blocking query waits for changes
+	watchWaitTime = 30 * time.Second
+
+	// watchRetryDelay is how long a watcher waits after a failed query
+	watchRetryDelay = 5 * time.Second
+)
+
+// blockingQuery runs a nomad query honoring the given wait index
+type blockingQuery func(options *nomad.QueryOptions) (*nomad.QueryMeta, error)
+
+// panelWatch describes how a panel is kept up to date
+type panelWatch struct {
+	viewName  string
+	query     blockingQuery
+	render    viewHandlerCallback
+	selection cursorCallback
+}
+
+// startWatch keeps a panel up to date by running a blocking query in the
+// background and redrawing the panel whenever the query index moves.  Any
+// watcher previously attached to the panel is stopped.
+func (trekState *trekStateType) startWatch(g *gocui.Gui, watch panelWatch) {
+	stop := trekState.addWatcher(watch.viewName)
+
+	go func() {
+		var waitIndex uint64
+		for {
+			meta, err := watch.query(&nomad.QueryOptions{WaitIndex: waitIndex, WaitTime: watchWaitTime})
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			if err != nil {
+				select {
+				case <-stop:
+					return
+				case <-time.After(watchRetryDelay):
+				}
+				continue
+			}
+
+			if waitIndex != 0 && meta.LastIndex != waitIndex {
+				g.Update(func(g *gocui.Gui) error {
+					select {
+					case <-stop:
+						return nil
+					default:
+					}
+					v, err := g.View(watch.viewName)
+					if err != nil {
+						return nil
+					}
+					return redrawView(v, trekState, watch.render, watch.selection)
+				})
+			}
+			waitIndex = meta.LastIndex
+		}
+	}()
+}
+
+// addWatcher registers a background job keeping a panel up to date, stopping
+// any job previously attached to the panel.  The job must return once the
+// returned channel is closed.
+func (trekState *trekStateType) addWatcher(viewName string) chan struct{} {
+	trekState.stopWatch(viewName)
+
+	if trekState.watchers == nil {
+		trekState.watchers = make(map[string]chan struct{})
+	}
+	stop := make(chan struct{})
+	trekState.watchers[viewName] = stop
+	return stop
+}
+
+// stopWatch stops the watcher attached to a panel, if any
+func (trekState *trekStateType) stopWatch(viewName string) {
+	if stop, ok := trekState.watchers[viewName]; ok {
+		close(stop)
+		delete(trekState.watchers, viewName)
+	}
+}
+
+// lineKey identifies the object displayed on a line, ignoring the details
+// (status, counts) that may change between two redraws
+func lineKey(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// redrawView renders a view again, keeping the cursor on the same object
+func redrawView(v *gocui.View, trekState *trekStateType, render viewHandlerCallback, selection cursorCallback) error {
+	ox, oy := v.Origin()
+	cx, cy := v.Cursor()
+	selectedLine, _ := v.Line(cy)
+
+	v.Clear()
+	if err := render(v, trekState); err != nil {
+		return err
+	}
+
+	lines := v.BufferLines()
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	selected := oy + cy
+	for index, line := range lines {
+		if key := lineKey(line); key != "" && key == lineKey(selectedLine) {
+			selected = index
+			break
+		}
+	}
+	if selected > len(lines)-1 {
+		selected = len(lines) - 1
+	}
+	if selected < 0 {
+		selected = 0
+	}
+
+	_, height := v.Size()
+	if selected < oy || selected-oy >= height {
+		oy = selected
+	}
+	if err := v.SetOrigin(ox, oy); err != nil {
+		return err
+	}
+	v.SetCursor(cx, selected-oy)
+
+	if selection != nil {
+		selection(trekState, cursorPosition{x: cx, y: selected})
+	}
+	return nil
+}